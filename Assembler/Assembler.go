@@ -0,0 +1,283 @@
+package assembler
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Number of bytes in a data word
+const WORD_SIZE = 4
+
+// Struct to hold the state of a file being assembled
+type assembly struct {
+	object     Object
+	section    string
+	globals    []Statement
+	references []Statement
+	constants  map[string]int64
+	errors     ErrorList
+}
+
+// Assemble is a function to turn preprocessed statements into a relocatable object.
+// Constants defined with .equ or .set are added to its symbol table.
+func Assemble(statements []Statement, constants map[string]int64) (*Object, error) {
+	current := assembly{section: TEXT_SECTION, constants: constants}
+
+	var names []string
+	for name := range constants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		current.object.Symbols = append(current.object.Symbols, Symbol{Name: name, Section: ABSOLUTE_SECTION, Value: constants[name]})
+	}
+
+	for _, statement := range statements {
+		current.assembleStatement(statement)
+	}
+	current.checkReferences()
+
+	return &current.object, current.errors.asError()
+}
+
+// Method to record an error at the location of a statement.
+func (current *assembly) fail(statement Statement, message string) {
+	current.errors = append(current.errors, locate(statement, errors.New(message)))
+}
+
+// Method to define a label at the current position of the current section.
+func (current *assembly) defineLabel(statement Statement, label string) {
+	if previous, isDefined := current.object.lookup(label); isDefined {
+		if previous.Section == ABSOLUTE_SECTION {
+			current.fail(statement, "Label "+label+" is already defined as a constant")
+		} else {
+			current.fail(statement, "Duplicate label "+label+", first defined at "+previous.Location.String())
+		}
+		return
+	}
+
+	symbol := Symbol{Name: label, Section: current.section, Location: statement.Location}
+	if current.section == TEXT_SECTION {
+		symbol.Value = int64(len(current.object.Text))
+	} else {
+		symbol.Value = int64(len(current.object.Data) * WORD_SIZE)
+	}
+	current.object.Symbols = append(current.object.Symbols, symbol)
+}
+
+// Method to assemble a single statement into the current section.
+func (current *assembly) assembleStatement(statement Statement) {
+	text := statement.Text
+	for {
+		label, rest := splitLabel(text)
+		if label == "" {
+			break
+		}
+		current.defineLabel(statement, label)
+		text = rest
+	}
+
+	if strings.HasPrefix(text, ".") {
+		current.assembleDirective(statement, text)
+		return
+	}
+
+	if current.section != TEXT_SECTION {
+		if text != "" {
+			current.fail(statement, "Instruction in data section : "+text)
+		}
+		return
+	}
+
+	if text == "" {
+		// a statement with only a label is kept as an empty instruction (NoOp)
+		current.object.Text = append(current.object.Text, Statement{Location: statement.Location})
+		return
+	}
+
+	parsed, err := ParseInstruction(text)
+	if err != nil {
+		current.fail(statement, err.Error())
+		return
+	}
+	expansion, isPseudo, err := expandPseudoInstruction(parsed)
+	if err != nil {
+		current.fail(statement, err.Error())
+		return
+	}
+	if !isPseudo {
+		expansion = []string{parsed.String()}
+	}
+
+	for _, instruction := range expansion {
+		index := int64(len(current.object.Text))
+		entry := Statement{Text: instruction, Location: statement.Location}
+		if isPseudo {
+			entry.Alias = parsed.String()
+		}
+		current.object.Text = append(current.object.Text, entry)
+
+		parsedEntry, err := ParseInstruction(instruction)
+		if err != nil {
+			current.fail(statement, err.Error())
+			continue
+		}
+		if target, isBranch := branchTarget(parsedEntry); isBranch {
+			current.addRelocation(entry, TEXT_SECTION, BRANCH_RELOCATION, index, target)
+		} else {
+			for _, symbol := range expressionSymbols(parsedEntry) {
+				current.addRelocation(entry, TEXT_SECTION, ADDRESS_RELOCATION, index, symbol)
+			}
+		}
+	}
+}
+
+// Method to record a reference to a symbol made by a statement.
+func (current *assembly) addRelocation(statement Statement, section, kind string, offset int64, symbol string) {
+	current.object.Relocations = append(current.object.Relocations, Relocation{Section: section, Type: kind, Offset: offset, Symbol: symbol})
+	current.references = append(current.references, statement)
+}
+
+// Method to handle section, symbol and data directives.
+func (current *assembly) assembleDirective(statement Statement, text string) {
+	directive, operands := splitDirective(text)
+
+	switch strings.ToLower(directive) {
+
+	case ".text":
+		current.section = TEXT_SECTION
+
+	case ".data":
+		current.section = DATA_SECTION
+
+	case ".global", ".globl":
+		current.globals = append(current.globals, statement)
+
+	case ".extern":
+		for _, name := range splitArguments(operands) {
+			current.object.Externs = append(current.object.Externs, name)
+		}
+
+	case ".word":
+		if current.section != DATA_SECTION {
+			current.fail(statement, ".word is only allowed in the data section")
+			return
+		}
+		for _, operand := range splitArguments(operands) {
+			value, err := Evaluate(operand, current.constants)
+			if err != nil && isSymbolName(operand) {
+				// address of a label, filled in by the linker
+				current.addRelocation(statement, DATA_SECTION, ADDRESS_RELOCATION, int64(len(current.object.Data)), operand)
+			} else if err != nil {
+				current.fail(statement, err.Error())
+			}
+			current.object.Data = append(current.object.Data, int32(value))
+		}
+
+	case ".space":
+		if current.section != DATA_SECTION {
+			current.fail(statement, ".space is only allowed in the data section")
+			return
+		}
+		size, err := Evaluate(operands, current.constants)
+		if err != nil {
+			current.fail(statement, err.Error())
+			return
+		}
+		if size < 0 {
+			current.fail(statement, "Negative size in "+text)
+			return
+		}
+		// sizes are rounded up to whole words
+		for i := int64(0); i < size; i += WORD_SIZE {
+			current.object.Data = append(current.object.Data, 0)
+		}
+
+	default:
+		current.fail(statement, "Unknown directive "+directive)
+	}
+}
+
+// Method to check that global symbols are defined and that every reference
+// is either defined in this file or declared with .extern.
+func (current *assembly) checkReferences() {
+	for _, statement := range current.globals {
+		_, operands := splitDirective(statement.Text)
+		for _, name := range splitArguments(operands) {
+			found := false
+			for i := range current.object.Symbols {
+				if current.object.Symbols[i].Name == name {
+					current.object.Symbols[i].Global = true
+					found = true
+				}
+			}
+			if !found {
+				current.fail(statement, "Global symbol "+name+" is not defined")
+			}
+		}
+	}
+
+	for i, relocation := range current.object.Relocations {
+		if _, isDefined := current.object.lookup(relocation.Symbol); isDefined || current.isExtern(relocation.Symbol) {
+			continue
+		}
+		statement := current.references[i]
+		if relocation.Type == BRANCH_RELOCATION {
+			current.fail(statement, "Undefined label "+relocation.Symbol+" in "+statement.Text)
+		} else {
+			current.fail(statement, "Undefined symbol "+relocation.Symbol+" in "+statement.Text)
+		}
+	}
+}
+
+// Method to check if a symbol was declared with .extern.
+func (current *assembly) isExtern(name string) bool {
+	for _, extern := range current.object.Externs {
+		if extern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Function to check if an operand is a plain symbol name.
+func isSymbolName(operand string) bool {
+	return symbolRegex.MatchString(operand)
+}
+
+// Function to find the label operand of a branch instruction.
+func branchTarget(instruction *Instruction) (string, bool) {
+	mnemonic := instruction.Mnemonic
+	if mnemonic != "B" && mnemonic != "BL" && mnemonic != "CBZ" && mnemonic != "CBNZ" && !strings.HasPrefix(mnemonic, "B.") {
+		return "", false
+	}
+	if len(instruction.Operands) == 0 {
+		return "", false
+	}
+	target := instruction.Operands[len(instruction.Operands)-1]
+	return target.Expression, target.Kind == LABEL_OPERAND
+}
+
+// Function to find the symbols used in the constant expressions of an instruction,
+// which are its immediates, address offsets, shift amounts and labels.
+func expressionSymbols(instruction *Instruction) []string {
+	var symbols []string
+	found := make(map[string]bool)
+	for _, operand := range instruction.Operands {
+		if operand.Kind == REGISTER_OPERAND {
+			continue
+		}
+		tokens, err := tokenizeExpression(operand.Expression)
+		if err != nil {
+			continue
+		}
+		for _, token := range tokens {
+			if isSymbolName(token) && !found[token] {
+				found[token] = true
+				symbols = append(symbols, token)
+			}
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,302 @@
+package assembler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Precedence of binary operators, higher binds tighter
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, "<=": 7, ">": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+// Struct to hold the state of an expression being evaluated
+type expressionParser struct {
+	expr    string
+	tokens  []string
+	index   int
+	symbols map[string]int64
+}
+
+// Evaluate is a function to compute the value of a constant expression.
+// Identifiers are looked up in the given symbol table.
+func Evaluate(expr string, symbols map[string]int64) (int64, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 0 {
+		return 0, errors.New("Missing expression")
+	}
+
+	parser := expressionParser{expr: expr, tokens: tokens, symbols: symbols}
+	value, err := parser.parseBinary(1)
+	if err != nil {
+		return 0, err
+	}
+	if parser.index < len(parser.tokens) {
+		return 0, errors.New("Unexpected '" + parser.tokens[parser.index] + "' in expression " + expr)
+	}
+	return value, nil
+}
+
+// UsesSymbols is a function to check if an expression refers to any symbol,
+// in which case it can only be evaluated once the symbols have values.
+func UsesSymbols(expr string) bool {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return false
+	}
+	for _, token := range tokens {
+		if isSymbolName(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdentifierChar is a function to check if a character can be part of a symbol name.
+func isIdentifierChar(char byte) bool {
+	return char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
+
+// Function to split an expression into numbers, identifiers and operators.
+func tokenizeExpression(expr string) ([]string, error) {
+	var tokens []string
+	i := 0
+	for i < len(expr) {
+		char := expr[i]
+		if char == ' ' || char == '\t' || char == '\n' || char == '\r' {
+			i++
+			continue
+		}
+		if char == '\'' {
+			// character literal, possibly with an escape sequence
+			start := i
+			i++
+			for i < len(expr) && expr[i] != '\'' {
+				if expr[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(expr) {
+				return nil, errors.New("Unterminated character literal in expression " + expr)
+			}
+			i++
+			tokens = append(tokens, expr[start:i])
+			continue
+		}
+		if isIdentifierChar(char) {
+			start := i
+			for i < len(expr) && isIdentifierChar(expr[i]) {
+				i++
+			}
+			tokens = append(tokens, expr[start:i])
+			continue
+		}
+		if i+1 < len(expr) {
+			if _, isOperator := binaryPrecedence[expr[i:i+2]]; isOperator {
+				tokens = append(tokens, expr[i:i+2])
+				i += 2
+				continue
+			}
+		}
+		if strings.IndexByte("+-*/%&|^~!()<>", char) == -1 {
+			return nil, errors.New("Invalid character '" + string(char) + "' in expression " + expr)
+		}
+		tokens = append(tokens, string(char))
+		i++
+	}
+	return tokens, nil
+}
+
+// Method to return the next token without consuming it.
+func (parser *expressionParser) peek() string {
+	if parser.index < len(parser.tokens) {
+		return parser.tokens[parser.index]
+	}
+	return ""
+}
+
+// Method to parse binary operators with at least the given precedence.
+func (parser *expressionParser) parseBinary(minPrecedence int) (int64, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator := parser.peek()
+		precedence, isOperator := binaryPrecedence[operator]
+		if !isOperator || precedence < minPrecedence {
+			return left, nil
+		}
+		parser.index++
+		right, err := parser.parseBinary(precedence + 1)
+		if err != nil {
+			return 0, err
+		}
+		left, err = applyOperator(operator, left, right)
+		if err != nil {
+			return 0, errors.New(err.Error() + " in expression " + parser.expr)
+		}
+	}
+}
+
+// Method to parse unary operators, parenthesised expressions and operands.
+func (parser *expressionParser) parseUnary() (int64, error) {
+	token := parser.peek()
+	switch token {
+	case "":
+		return 0, errors.New("Unexpected end of expression " + parser.expr)
+	case "-", "+", "~", "!":
+		parser.index++
+		value, err := parser.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch token {
+		case "-":
+			return -value, nil
+		case "~":
+			return ^value, nil
+		case "!":
+			return boolToInt(value == 0), nil
+		}
+		return value, nil
+	case "(":
+		parser.index++
+		value, err := parser.parseBinary(1)
+		if err != nil {
+			return 0, err
+		}
+		if parser.peek() != ")" {
+			return 0, errors.New("Missing ')' in expression " + parser.expr)
+		}
+		parser.index++
+		return value, nil
+	}
+
+	parser.index++
+	if token[0] >= '0' && token[0] <= '9' {
+		value, err := parseNumber(token)
+		if err != nil {
+			return 0, errors.New("Invalid number " + token + " in expression " + parser.expr)
+		}
+		return value, nil
+	}
+	if token[0] == '\'' {
+		value, err := parseCharacter(token)
+		if err != nil {
+			return 0, errors.New("Invalid character literal " + token + " in expression " + parser.expr)
+		}
+		return value, nil
+	}
+	if isIdentifierChar(token[0]) {
+		value, isDefined := parser.symbols[token]
+		if !isDefined {
+			return 0, errors.New("Undefined symbol " + token + " in expression " + parser.expr)
+		}
+		return value, nil
+	}
+	return 0, errors.New("Unexpected '" + token + "' in expression " + parser.expr)
+}
+
+// Function to parse a decimal, hexadecimal (0x), binary (0b) or octal (0o or leading 0) number.
+// Numbers up to 64 bits are accepted, so 0xFFFFFFFFFFFFFFFF is -1.
+func parseNumber(token string) (int64, error) {
+	base := 10
+	digits := strings.ToLower(token)
+	if strings.HasPrefix(digits, "0x") {
+		base, digits = 16, digits[2:]
+	} else if strings.HasPrefix(digits, "0b") {
+		base, digits = 2, digits[2:]
+	} else if strings.HasPrefix(digits, "0o") {
+		base, digits = 8, digits[2:]
+	} else if len(digits) > 1 && digits[0] == '0' {
+		base, digits = 8, digits[1:]
+	}
+	value, err := strconv.ParseUint(digits, base, 64)
+	return int64(value), err
+}
+
+// Function to parse a quoted character literal such as 'A' or '\n'.
+func parseCharacter(token string) (int64, error) {
+	value, _, tail, err := strconv.UnquoteChar(token[1:len(token)-1], '\'')
+	if err != nil {
+		return 0, err
+	}
+	if tail != "" {
+		return 0, errors.New("More than one character")
+	}
+	return int64(value), nil
+}
+
+// Function to apply a binary operator to two values.
+func applyOperator(operator string, left, right int64) (int64, error) {
+	switch operator {
+	case "||":
+		return boolToInt(left != 0 || right != 0), nil
+	case "&&":
+		return boolToInt(left != 0 && right != 0), nil
+	case "|":
+		return left | right, nil
+	case "^":
+		return left ^ right, nil
+	case "&":
+		return left & right, nil
+	case "==":
+		return boolToInt(left == right), nil
+	case "!=":
+		return boolToInt(left != right), nil
+	case "<":
+		return boolToInt(left < right), nil
+	case "<=":
+		return boolToInt(left <= right), nil
+	case ">":
+		return boolToInt(left > right), nil
+	case ">=":
+		return boolToInt(left >= right), nil
+	case "<<", ">>":
+		if right < 0 {
+			return 0, errors.New("Negative shift amount")
+		}
+		if operator == "<<" {
+			return left << uint64(right), nil
+		}
+		return left >> uint64(right), nil
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/", "%":
+		if right == 0 {
+			return 0, errors.New("Division by zero")
+		}
+		if operator == "/" {
+			return left / right, nil
+		}
+		return left % right, nil
+	}
+	return 0, errors.New("Unknown operator " + operator)
+}
+
+// Function to convert a boolean into 0 or 1.
+func boolToInt(value bool) int64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package assembler
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	symbols := map[string]int64{"SIZE": 8, "base_1": 0x100}
+	tests := []struct {
+		expr string
+		want int64
+	}{
+		// radix prefixes
+		{"42", 42},
+		{"0", 0},
+		{"0x2A", 42},
+		{"0X2a", 42},
+		{"0b101010", 42},
+		{"0B101010", 42},
+		{"0o52", 42},
+		{"052", 42},
+		// characters
+		{"'A'", 65},
+		{"'\\n'", 10},
+		{"'\\''", 39},
+		{"'\\x41'", 65},
+		{"' '", 32},
+		// precedence
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"64 / 4 / 2", 8},
+		{"7 % 4 * 2", 6},
+		{"1 << 2 + 1", 8},
+		{"1 + 2 == 3", 1},
+		{"1 < 2 == 1", 1},
+		{"6 & 3 ^ 1 | 8", 11},
+		{"1 | 2 && 0", 0},
+		{"0 && 1 || 1", 1},
+		{"-2 * -3", 6},
+		{"~0", -1},
+		{"!5 + !0", 1},
+		{"- (3 - 5)", 2},
+		{"-0x10 >> 2", -4},
+		// symbols
+		{"SIZE * 4 + base_1", 0x120},
+		{"SIZE>=8", 1},
+		// overflow wraps around in 64 bits
+		{"0xFFFFFFFFFFFFFFFF", -1},
+		{"0x8000000000000000", math.MinInt64},
+		{"9223372036854775807 + 1", math.MinInt64},
+		{"1 << 63", math.MinInt64},
+		{"1 << 64", 0},
+		{"-9223372036854775807 - 1", math.MinInt64},
+	}
+	for _, test := range tests {
+		value, err := Evaluate(test.expr, symbols)
+		if err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", test.expr, err)
+		} else if value != test.want {
+			t.Errorf("Evaluate(%q) = %d, want %d", test.expr, value, test.want)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "Missing expression"},
+		{"0x", "Invalid number 0x"},
+		{"0b102", "Invalid number 0b102"},
+		{"08", "Invalid number 08"},
+		{"0x10000000000000000", "Invalid number 0x10000000000000000"},
+		{"18446744073709551616", "Invalid number 18446744073709551616"},
+		{"'ab'", "Invalid character literal 'ab'"},
+		{"'a", "Unterminated character literal"},
+		{"1 / 0", "Division by zero"},
+		{"1 % (2 - 2)", "Division by zero"},
+		{"1 << -1", "Negative shift amount"},
+		{"(1 + 2", "Missing ')'"},
+		{"1 + 2)", "Unexpected ')'"},
+		{"1 +", "Unexpected end of expression"},
+		{"1 2", "Unexpected '2'"},
+		{"MISSING + 1", "Undefined symbol MISSING"},
+		{"1 $ 2", "Invalid character '$'"},
+	}
+	for _, test := range tests {
+		_, err := Evaluate(test.expr, nil)
+		if err == nil {
+			t.Errorf("Evaluate(%q) succeeded, want an error", test.expr)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Evaluate(%q) failed with %q, want %q", test.expr, err.Error(), test.want)
+		}
+	}
+}
+
+func TestUsesSymbols(t *testing.T) {
+	tests := map[string]bool{
+		"1 + 2":      false,
+		"0x1F":       false,
+		"'A'":        false,
+		"SIZE + 1":   true,
+		"(label)":    true,
+		"1 + 0b1 *2": false,
+	}
+	for expr, want := range tests {
+		if got := UsesSymbols(expr); got != want {
+			t.Errorf("UsesSymbols(%q) = %t, want %t", expr, got, want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package assembler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Kinds of instruction operands
+const (
+	REGISTER_OPERAND  = "register"
+	IMMEDIATE_OPERAND = "immediate"
+	ADDRESS_OPERAND   = "memory address"
+	SHIFT_OPERAND     = "shift"
+	LABEL_OPERAND     = "label"
+)
+
+// Register numbers of the registers that have a name
+var namedRegisters = map[string]uint{
+	"SP":  28,
+	"FP":  29,
+	"LR":  30,
+	"XZR": 31,
+}
+
+// Operand is a single operand of an instruction.
+// Text is the operand in canonical form. Immediates, address offsets and shift amounts
+// keep their constant expression in Expression, while labels keep their name there.
+// Register holds the register of a register operand, or the base register of an address.
+type Operand struct {
+	Kind       string
+	Text       string
+	Register   uint
+	Expression string
+}
+
+// Instruction is an instruction split into its upper case mnemonic and its operands.
+type Instruction struct {
+	Mnemonic string
+	Operands []Operand
+}
+
+// Struct to hold the state of an instruction being parsed
+type instructionParser struct {
+	text    string
+	tokens  []token
+	index   int
+	operand int
+}
+
+// ParseInstruction is a function to split an instruction into its mnemonic and operands.
+// Mnemonics and registers may be written in any case, and any amount of whitespace is accepted.
+func ParseInstruction(text string) (*Instruction, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	parser := instructionParser{text: text, tokens: tokens}
+	if len(tokens) == 0 || tokens[0].kind != IDENTIFIER_TOKEN {
+		return nil, parser.unexpected("mnemonic")
+	}
+
+	instruction := Instruction{Mnemonic: strings.ToUpper(tokens[0].text)}
+	parser.index = 1
+	for parser.index < len(parser.tokens) {
+		if parser.operand > 0 {
+			if parser.peek().text != "," {
+				return nil, parser.unexpected("','")
+			}
+			parser.index++
+		}
+		parser.operand++
+		operand, err := parser.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		instruction.Operands = append(instruction.Operands, operand)
+	}
+	return &instruction, nil
+}
+
+// String is a method to write an instruction in canonical form, e.g. "ADD X1, X2, X3".
+func (instruction *Instruction) String() string {
+	if len(instruction.Operands) == 0 {
+		return instruction.Mnemonic
+	}
+	operands := make([]string, len(instruction.Operands))
+	for i, operand := range instruction.Operands {
+		operands[i] = operand.Text
+	}
+	return instruction.Mnemonic + " " + strings.Join(operands, ", ")
+}
+
+// CheckOperands is a method to check that an instruction has exactly the given kinds of operands.
+// A kind may list alternatives, as in "register or immediate".
+func (instruction *Instruction) CheckOperands(kinds ...string) error {
+	if len(instruction.Operands) != len(kinds) {
+		return errors.New("Expected " + strconv.Itoa(len(kinds)) + " operands, found " + strconv.Itoa(len(instruction.Operands)) + " in " + instruction.String())
+	}
+	for i, operand := range instruction.Operands {
+		isExpected := false
+		for _, kind := range strings.Split(kinds[i], " or ") {
+			isExpected = isExpected || operand.Kind == kind
+		}
+		if !isExpected {
+			return errors.New("Expected " + kinds[i] + ", found '" + operand.Text + "' in operand " + strconv.Itoa(i+1) + " of " + instruction.String())
+		}
+	}
+	return nil
+}
+
+// RegisterNumber is a function to find the number of a register such as X5, XZR, SP or LR.
+func RegisterNumber(name string) (uint, bool) {
+	name = strings.ToUpper(name)
+	if number, isNamed := namedRegisters[name]; isNamed {
+		return number, true
+	}
+	if len(name) < 2 || name[0] != 'X' || (len(name) > 2 && name[1] == '0') {
+		return 0, false
+	}
+	number, err := strconv.Atoi(name[1:])
+	if err != nil || number < 0 || number > 30 {
+		return 0, false
+	}
+	return uint(number), true
+}
+
+// Method to return the next token without consuming it.
+// An empty token marks the end of the instruction.
+func (parser *instructionParser) peek() token {
+	if parser.index < len(parser.tokens) {
+		return parser.tokens[parser.index]
+	}
+	return token{}
+}
+
+// Method to describe what was found instead of the expected part of an instruction.
+func (parser *instructionParser) unexpected(expected string) error {
+	found := "end of instruction"
+	if parser.index < len(parser.tokens) {
+		found = "'" + parser.tokens[parser.index].text + "'"
+	}
+	where := ""
+	if parser.operand > 0 {
+		where = " in operand " + strconv.Itoa(parser.operand)
+	}
+	return errors.New("Expected " + expected + ", found " + found + where + " of " + strings.TrimSpace(parser.text))
+}
+
+// Method to check if the next token ends the current operand.
+func (parser *instructionParser) atOperandEnd() bool {
+	next := parser.peek().text
+	return next == "" || next == ","
+}
+
+// Method to parse a single operand.
+func (parser *instructionParser) parseOperand() (Operand, error) {
+	current := parser.peek()
+
+	switch current.text {
+	case "[":
+		return parser.parseAddress()
+	case "#":
+		parser.index++
+		expression, err := parser.parseExpression()
+		if err != nil {
+			return Operand{}, err
+		}
+		return Operand{Kind: IMMEDIATE_OPERAND, Text: "#" + expression, Expression: expression}, nil
+	}
+
+	if current.kind == IDENTIFIER_TOKEN {
+		if register, isRegister := RegisterNumber(current.text); isRegister {
+			parser.index++
+			if !parser.atOperandEnd() {
+				return Operand{}, parser.unexpected("','")
+			}
+			return Operand{Kind: REGISTER_OPERAND, Text: strings.ToUpper(current.text), Register: register}, nil
+		}
+
+		if strings.ToUpper(current.text) == "LSL" {
+			parser.index++
+			prefix := "LSL "
+			if parser.peek().text == "#" {
+				parser.index++
+				prefix += "#"
+			}
+			expression, err := parser.parseExpression()
+			if err != nil {
+				return Operand{}, err
+			}
+			return Operand{Kind: SHIFT_OPERAND, Text: prefix + expression, Expression: expression}, nil
+		}
+
+		next := parser.index + 1
+		if isSymbolName(current.text) && (next == len(parser.tokens) || parser.tokens[next].text == ",") {
+			parser.index++
+			return Operand{Kind: LABEL_OPERAND, Text: current.text, Expression: current.text}, nil
+		}
+	}
+
+	// anything else is a constant written without '#'
+	expression, err := parser.parseExpression()
+	if err != nil {
+		return Operand{}, err
+	}
+	return Operand{Kind: IMMEDIATE_OPERAND, Text: expression, Expression: expression}, nil
+}
+
+// Method to parse a memory address such as [X2, #40] or [X2].
+func (parser *instructionParser) parseAddress() (Operand, error) {
+	parser.index++
+	base := parser.peek()
+	register, isRegister := RegisterNumber(base.text)
+	if base.kind != IDENTIFIER_TOKEN || !isRegister {
+		return Operand{}, parser.unexpected("base register")
+	}
+	parser.index++
+
+	operand := Operand{Kind: ADDRESS_OPERAND, Register: register, Expression: "0"}
+	operand.Text = "[" + strings.ToUpper(base.text)
+	if parser.peek().text == "," {
+		parser.index++
+		if parser.peek().text == "#" {
+			parser.index++
+		}
+		expression, err := parser.parseExpression()
+		if err != nil {
+			return Operand{}, err
+		}
+		operand.Expression = expression
+		operand.Text += ", #" + expression
+	}
+
+	if parser.peek().text != "]" {
+		return Operand{}, parser.unexpected("']'")
+	}
+	parser.index++
+	operand.Text += "]"
+
+	if !parser.atOperandEnd() {
+		return Operand{}, parser.unexpected("','")
+	}
+	return operand, nil
+}
+
+// Method to parse a constant expression, which ends at a ',' or ']' outside parentheses.
+// The expression is returned as written and evaluated later.
+func (parser *instructionParser) parseExpression() (string, error) {
+	start := parser.index
+	depth := 0
+	for parser.index < len(parser.tokens) {
+		current := parser.tokens[parser.index]
+		if depth == 0 && (current.text == "," || current.text == "]") {
+			break
+		}
+		switch current.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case "#", "[", "]":
+			return "", parser.unexpected("expression")
+		}
+		parser.index++
+	}
+	if parser.index == start {
+		return "", parser.unexpected("expression")
+	}
+
+	last := parser.tokens[parser.index-1]
+	return parser.text[parser.tokens[start].position : last.position+len(last.text)], nil
+}
@@ -0,0 +1,74 @@
+package assembler
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kinds of tokens in an instruction
+const (
+	IDENTIFIER_TOKEN  = "identifier"
+	NUMBER_TOKEN      = "number"
+	CHARACTER_TOKEN   = "character"
+	PUNCTUATION_TOKEN = "punctuation"
+)
+
+// Struct to represent a word, number or symbol of an instruction.
+// Position is the byte offset of the token in the instruction.
+type token struct {
+	kind     string
+	text     string
+	position int
+}
+
+// Function to split an instruction into tokens.
+// Whitespace only separates tokens, so any amount of it is accepted.
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(text) {
+		char := text[i]
+		start := i
+
+		switch {
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+			i++
+			continue
+
+		case char >= '0' && char <= '9':
+			for i < len(text) && isIdentifierChar(text[i]) {
+				i++
+			}
+			tokens = append(tokens, token{NUMBER_TOKEN, text[start:i], start})
+
+		case isIdentifierChar(char):
+			// dots are allowed after the first character for mnemonics such as B.EQ
+			for i < len(text) && (isIdentifierChar(text[i]) || text[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{IDENTIFIER_TOKEN, text[start:i], start})
+
+		case char == '\'':
+			i++
+			for i < len(text) && text[i] != '\'' {
+				if text[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(text) {
+				return nil, errors.New("Unterminated character literal in " + text)
+			}
+			i++
+			tokens = append(tokens, token{CHARACTER_TOKEN, text[start:i], start})
+
+		case strings.IndexByte("#[],+-*/%&|^~!()<>=", char) != -1:
+			i++
+			tokens = append(tokens, token{PUNCTUATION_TOKEN, text[start:i], start})
+
+		default:
+			return nil, errors.New("Invalid character '" + string(char) + "' in " + text)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,144 @@
+package assembler
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Link is a function to merge objects into a single program.
+// Global symbols are shared by all objects, while local symbols that clash with
+// a name used elsewhere are renamed. Duplicate and undefined symbols are reported together.
+// Execution starts in the first object, whose instructions are placed last so that
+// running past its end still ends the program.
+func Link(objects []*Object) (*Object, error) {
+	var linked Object
+	var errorList ErrorList
+
+	textOrder := make([]int, len(objects))
+	for i := range objects {
+		textOrder[i] = (i + 1) % len(objects)
+	}
+
+	textBase := make([]int64, len(objects))
+	dataBase := make([]int64, len(objects))
+	var textSize, dataSize int64
+	for i, index := range textOrder {
+		textBase[index] = textSize
+		textSize += int64(len(objects[index].Text))
+		dataBase[i] = dataSize
+		dataSize += int64(len(objects[i].Data) * WORD_SIZE)
+	}
+
+	// moves a symbol to its place in the linked program
+	place := func(symbol Symbol, index int) Symbol {
+		switch symbol.Section {
+		case TEXT_SECTION:
+			symbol.Value += textBase[index]
+		case DATA_SECTION:
+			symbol.Value += dataBase[index]
+		}
+		return symbol
+	}
+
+	// global symbols keep their names
+	globals := make(map[string]Symbol)
+	taken := make(map[string]bool)
+	for i, object := range objects {
+		for _, symbol := range object.Symbols {
+			if !symbol.Global {
+				continue
+			}
+			if previous, isDefined := globals[symbol.Name]; isDefined {
+				errorList = append(errorList, errors.New(symbol.Location.String()+": Duplicate global symbol "+symbol.Name+", first defined at "+previous.Location.String()))
+				continue
+			}
+			globals[symbol.Name] = place(symbol, i)
+			taken[symbol.Name] = true
+			linked.Symbols = append(linked.Symbols, globals[symbol.Name])
+		}
+	}
+
+	// local symbols are renamed if their name is already in use
+	locals := make([]map[string]Symbol, len(objects))
+	for i, object := range objects {
+		locals[i] = make(map[string]Symbol)
+		for _, symbol := range object.Symbols {
+			if symbol.Global {
+				continue
+			}
+			localSymbol := place(symbol, i)
+			for suffix := 1; taken[localSymbol.Name]; suffix++ {
+				localSymbol.Name = symbol.Name + "_" + strconv.Itoa(suffix)
+			}
+			taken[localSymbol.Name] = true
+			locals[i][symbol.Name] = localSymbol
+			linked.Symbols = append(linked.Symbols, localSymbol)
+		}
+	}
+
+	linked.Text = make([]Statement, textSize)
+	linked.Data = make([]int32, dataSize/WORD_SIZE)
+	for i, object := range objects {
+		textStart := int(textBase[i])
+		dataStart := int(dataBase[i] / WORD_SIZE)
+		copy(linked.Text[textStart:], object.Text)
+		copy(linked.Data[dataStart:], object.Data)
+
+		for _, relocation := range object.Relocations {
+			symbol, isLocal := locals[i][relocation.Symbol]
+			if !isLocal {
+				var isGlobal bool
+				symbol, isGlobal = globals[relocation.Symbol]
+				if !isGlobal {
+					errorList = append(errorList, undefinedSymbol(object, relocation))
+					continue
+				}
+			}
+
+			if relocation.Section == TEXT_SECTION {
+				statement := &linked.Text[textStart+int(relocation.Offset)]
+				if relocation.Type == ADDRESS_RELOCATION {
+					value := strconv.FormatInt(symbol.Value, 10)
+					if symbol.Value < 0 {
+						// keeps the value a single operand inside an expression
+						value = "(" + value + ")"
+					}
+					statement.Text = replaceSymbol(statement.Text, relocation.Symbol, value)
+				} else {
+					statement.Text = replaceSymbol(statement.Text, relocation.Symbol, symbol.Name)
+				}
+			} else {
+				linked.Data[dataStart+int(relocation.Offset)] += int32(symbol.Value)
+			}
+		}
+	}
+
+	if len(errorList) != 0 {
+		return nil, errorList
+	}
+	if len(objects) != 0 {
+		linked.Entry = textBase[0] + objects[0].Entry
+	}
+	return &linked, nil
+}
+
+// Function to describe a reference to a symbol that no object defines.
+func undefinedSymbol(object *Object, relocation Relocation) error {
+	if relocation.Section == TEXT_SECTION {
+		statement := object.Text[relocation.Offset]
+		return locate(statement, errors.New("Undefined symbol "+relocation.Symbol+" in "+statement.Text))
+	}
+	return errors.New("Undefined symbol " + relocation.Symbol + " in data word " + strconv.FormatInt(relocation.Offset, 10))
+}
+
+// Function to rename a symbol in the operands of an instruction.
+func replaceSymbol(instruction, oldName, newName string) string {
+	if oldName == newName {
+		return instruction
+	}
+	mnemonic, operands := splitDirective(instruction)
+	wordRegex := regexp.MustCompile("\\b" + regexp.QuoteMeta(oldName) + "\\b")
+	return strings.TrimSpace(mnemonic + " " + wordRegex.ReplaceAllString(operands, newName))
+}
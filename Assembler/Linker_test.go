@@ -0,0 +1,107 @@
+package assembler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Function to assemble source text into an object, failing the test on errors.
+func assembleText(t *testing.T, source string) *Object {
+	object, err := Assemble(statementsOf(source), nil)
+	if err != nil {
+		t.Fatalf("Assemble(%q) failed: %v", source, err)
+	}
+	return object
+}
+
+func TestLink(t *testing.T) {
+	main := assembleText(t, ".global main; .extern helper, table; main: BL helper; LDA X1, table; loop: B loop; .data; local: .word 5, table;")
+	lib := assembleText(t, ".global helper, table; helper: ADDI X3, X3, #1; loop: B loop; .data; .word 1; table: .word 7, local; local: .word 9;")
+	linked, err := Link([]*Object{main, lib})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first object is placed last, so that running past its end ends the program
+	var text []string
+	for _, statement := range linked.Text {
+		text = append(text, statement.Text)
+	}
+	wantText := []string{"ADDI X3, X3, #1", "B loop_1", "BL helper", "MOVZ X1, #12, LSL 0", "B loop"}
+	if !reflect.DeepEqual(text, wantText) {
+		t.Errorf("text = %q, want %q", text, wantText)
+	}
+	if linked.Entry != 2 {
+		t.Errorf("entry = %d, want 2", linked.Entry)
+	}
+	if alias := linked.Text[3].Alias; alias != "LDA X1, table" {
+		t.Errorf("alias = %q, want LDA X1, table", alias)
+	}
+
+	// data is placed in the order of the objects, with addresses of symbols filled in
+	wantData := []int32{5, 12, 1, 7, 20, 9}
+	if !reflect.DeepEqual(linked.Data, wantData) {
+		t.Errorf("data = %v, want %v", linked.Data, wantData)
+	}
+
+	wantSymbols := map[string]int64{"main": 2, "helper": 0, "table": 12, "loop": 4, "local": 0, "loop_1": 1, "local_1": 20}
+	symbols := make(map[string]int64)
+	for _, symbol := range linked.Symbols {
+		symbols[symbol.Name] = symbol.Value
+	}
+	if !reflect.DeepEqual(symbols, wantSymbols) {
+		t.Errorf("symbols = %v, want %v", symbols, wantSymbols)
+	}
+}
+
+func TestLinkNegativeAddress(t *testing.T) {
+	main := assembleText(t, ".extern OFFSET; ADDI X1, X1, #OFFSET + 1;")
+	constants, err := Assemble(statementsOf(".global OFFSET;"), map[string]int64{"OFFSET": -8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := Link([]*Object{main, constants})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := linked.Text[0].Text; text != "ADDI X1, X1, #(-8) + 1" {
+		t.Errorf("text = %q, want a parenthesised negative value", text)
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		want    []string
+	}{
+		{"duplicate global", []string{
+			".global f; f: B f;",
+			".global f;\nf: B f;",
+		}, []string{"test.s:2: Duplicate global symbol f, first defined at test.s:1"}},
+		{"undefined symbols", []string{
+			".extern f, g; BL f;\nLDA X1, g;",
+			"h: B h;",
+		}, []string{"test.s:1: Undefined symbol f in BL f", "test.s:2: Undefined symbol g in MOVZ X1, #g, LSL 0"}},
+		{"undefined data symbol", []string{
+			".extern f; .data; .word 1, f;",
+		}, []string{"Undefined symbol f in data word 1"}},
+	}
+	for _, test := range tests {
+		var objects []*Object
+		for _, source := range test.sources {
+			objects = append(objects, assembleText(t, source))
+		}
+		_, err := Link(objects)
+		if err == nil {
+			t.Errorf("%s: Link succeeded, want an error", test.name)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: Link failed with %q, want %q", test.name, err.Error(), want)
+			}
+		}
+	}
+}
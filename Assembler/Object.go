@@ -0,0 +1,145 @@
+package assembler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Section names used by symbols and relocations
+const (
+	TEXT_SECTION     = "text"
+	DATA_SECTION     = "data"
+	ABSOLUTE_SECTION = "abs"
+)
+
+// Kinds of relocation. Branch targets keep referring to a label by name,
+// while addresses are replaced by the value of the symbol.
+const (
+	BRANCH_RELOCATION  = "branch"
+	ADDRESS_RELOCATION = "address"
+)
+
+// Symbol is a label or constant defined by an object.
+// Text symbols hold an instruction index, data symbols a byte address, absolute symbols a constant.
+type Symbol struct {
+	Name     string   `json:"name"`
+	Section  string   `json:"section"`
+	Value    int64    `json:"value"`
+	Global   bool     `json:"global,omitempty"`
+	Location Location `json:"location"`
+}
+
+// Relocation records a reference from an instruction or a data word to a symbol
+// that can only be resolved once all objects are linked together.
+type Relocation struct {
+	Section string `json:"section"`
+	Type    string `json:"type"`
+	Offset  int64  `json:"offset"`
+	Symbol  string `json:"symbol"`
+}
+
+// Object is a separately assembled program.
+// Text offsets are instruction indices and data offsets are word indices.
+// Entry is the index of the first instruction to execute.
+type Object struct {
+	Entry       int64        `json:"entry"`
+	Text        []Statement  `json:"text"`
+	Data        []int32      `json:"data"`
+	Symbols     []Symbol     `json:"symbols"`
+	Externs     []string     `json:"externs,omitempty"`
+	Relocations []Relocation `json:"relocations,omitempty"`
+}
+
+// ErrorList is a collection of errors that are reported together.
+type ErrorList []error
+
+// Error is a method to join all errors of the list, one per line.
+func (errorList ErrorList) Error() string {
+	messages := make([]string, len(errorList))
+	for i, err := range errorList {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Append is a method to add an error to the list.
+// The errors of another ErrorList are added one by one.
+func (errorList ErrorList) Append(err error) ErrorList {
+	if other, isList := err.(ErrorList); isList {
+		return append(errorList, other...)
+	}
+	if err != nil {
+		errorList = append(errorList, err)
+	}
+	return errorList
+}
+
+// Method to return the list as an error, or nil if it is empty.
+func (errorList ErrorList) asError() error {
+	if len(errorList) == 0 {
+		return nil
+	}
+	return errorList
+}
+
+// Method to find a symbol defined by the object.
+func (object *Object) lookup(name string) (Symbol, bool) {
+	for _, symbol := range object.Symbols {
+		if symbol.Name == name {
+			return symbol, true
+		}
+	}
+	return Symbol{}, false
+}
+
+// IsObjectFile is a function to check if a file holds an object rather than source code.
+func IsObjectFile(fileName string) bool {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		char, err := reader.ReadByte()
+		if err != nil {
+			return false
+		}
+		if char != ' ' && char != '\t' && char != '\r' && char != '\n' {
+			return char == '{'
+		}
+	}
+}
+
+// ReadObject is a function to load an object file written by WriteObject.
+func ReadObject(fileName string) (*Object, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.New("Error opening file : " + err.Error())
+	}
+	defer file.Close()
+
+	var object Object
+	err = json.NewDecoder(file).Decode(&object)
+	if err != nil {
+		return nil, errors.New("Invalid object file " + fileName + " : " + err.Error())
+	}
+	return &object, nil
+}
+
+// WriteObject is a function to save an object to a file.
+func WriteObject(fileName string, object *Object) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return errors.New("Error creating file : " + err.Error())
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(object)
+}
@@ -0,0 +1,526 @@
+package assembler
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Maximum nesting of macro invocations, to catch recursive macros
+const MAX_MACRO_DEPTH = 64
+
+// Maximum repeat count of a .rept block, and number of statements it can expand to
+const MAX_REPEAT_STATEMENTS = 1 << 20
+
+// Regex matching a label at the start of a statement
+var labelRegex = regexp.MustCompile("^([a-zA-Z_][[:alnum:]_]*)[[:space:]]*:")
+
+// Regex matching a valid label or symbol name
+var symbolRegex = regexp.MustCompile("^[a-zA-Z_][[:alnum:]_]*$")
+
+// Struct to represent a macro defined with .macro/.endm
+type macro struct {
+	name       string
+	parameters []string
+	defaults   map[string]string
+	body       []Statement
+}
+
+// Struct to remember the macro invocation that made a renamed label
+type localLabel struct {
+	macro    string
+	location Location
+}
+
+// Struct to represent one level of .if/.else/.endif nesting
+type conditional struct {
+	isActive     bool
+	parentActive bool
+	hasElse      bool
+	opening      Statement
+}
+
+// Preprocessor expands included files, macros, repeated blocks and conditional blocks
+// so that only plain instructions and labels remain.
+type Preprocessor struct {
+	Symbols     map[string]int64
+	macros      map[string]*macro
+	counter     int
+	includes    []string
+	localLabels map[string]localLabel
+}
+
+// NewPreprocessor is a function to create a preprocessor with no macros or symbols defined.
+func NewPreprocessor() *Preprocessor {
+	return &Preprocessor{
+		Symbols:     make(map[string]int64),
+		macros:      make(map[string]*macro),
+		localLabels: make(map[string]localLabel),
+	}
+}
+
+// ExpandFile is a method to read a source file and run all preprocessor directives in it.
+func (preprocessor *Preprocessor) ExpandFile(fileName string) ([]Statement, error) {
+	statements, err := ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	absolutePath, err := filepath.Abs(fileName)
+	if err != nil {
+		return nil, err
+	}
+	preprocessor.includes = append(preprocessor.includes, absolutePath)
+	defer func() {
+		preprocessor.includes = preprocessor.includes[:len(preprocessor.includes)-1]
+	}()
+	return preprocessor.Expand(statements)
+}
+
+// Expand is a method to run all preprocessor directives in a list of statements.
+func (preprocessor *Preprocessor) Expand(statements []Statement) ([]Statement, error) {
+	expansion, err := preprocessor.expand(statements, 0)
+	if err != nil {
+		return nil, err
+	}
+	return expansion, preprocessor.checkLocalLabels(expansion)
+}
+
+// Method to check that no label of the program is spelled like a label renamed inside a macro,
+// which would otherwise be reported as a duplicate label in the program.
+func (preprocessor *Preprocessor) checkLocalLabels(statements []Statement) error {
+	locations := make(map[string][]Location)
+	for _, statement := range statements {
+		if label, _ := splitLabel(statement.Text); label != "" {
+			locations[label] = append(locations[label], statement.Location)
+		}
+	}
+	for _, statement := range statements {
+		label, _ := splitLabel(statement.Text)
+		renamed, isRenamed := preprocessor.localLabels[label]
+		if !isRenamed || len(locations[label]) < 2 {
+			continue
+		}
+		other := locations[label][0]
+		for _, location := range locations[label] {
+			if location != renamed.location {
+				other = location
+				break
+			}
+		}
+		message := "Label " + label + " of macro " + renamed.macro + " clashes with the label " + label + " at " + other.String() + ", rename one of them"
+		return errors.New(renamed.location.String() + ": " + message)
+	}
+	return nil
+}
+
+// Function to split a statement into its leading label (if any) and the rest.
+func splitLabel(statement string) (string, string) {
+	if !labelRegex.MatchString(statement) {
+		return "", statement
+	}
+	indexColon := strings.Index(statement, ":")
+	return strings.TrimSpace(statement[:indexColon]), strings.TrimSpace(statement[indexColon+1:])
+}
+
+// Function to split a statement into its first word and the remaining operands.
+func splitDirective(statement string) (string, string) {
+	indexSpace := strings.IndexAny(statement, " \t\r\n")
+	if indexSpace == -1 {
+		return statement, ""
+	}
+	return statement[:indexSpace], strings.TrimSpace(statement[indexSpace+1:])
+}
+
+// Function to attach a label to the first of a list of expanded statements.
+func attachLabel(label string, location Location, statements []Statement) []Statement {
+	if label == "" {
+		return statements
+	}
+	if len(statements) == 0 || labelRegex.MatchString(statements[0].Text) {
+		return append([]Statement{{Text: label + ":", Location: location}}, statements...)
+	}
+	statements[0].Text = label + ": " + statements[0].Text
+	return statements
+}
+
+// Function to collect the body of a block up to its closing directive.
+// Returns the body and the index of the closing statement.
+func collectBlock(statements []Statement, start int, open, close string) ([]Statement, int, error) {
+	depth := 1
+	for i := start + 1; i < len(statements); i++ {
+		_, statement := splitLabel(statements[i].Text)
+		directive, _ := splitDirective(statement)
+		directive = strings.ToLower(directive)
+		if directive == open {
+			if open == ".macro" {
+				return nil, 0, locate(statements[i], errors.New("Nested macro definition in "+statements[i].Text))
+			}
+			depth++
+		} else if directive == close {
+			if _, operands := splitDirective(statement); operands != "" {
+				return nil, 0, locate(statements[i], errors.New("Unexpected operands "+operands+" after "+close+", missing semicolon?"))
+			}
+			depth--
+			if depth == 0 {
+				return statements[start+1 : i], i, nil
+			}
+		}
+	}
+	return nil, 0, locate(statements[start], errors.New("Missing "+close+" for "+statements[start].Text))
+}
+
+// Method to expand a list of statements at the given macro nesting depth.
+func (preprocessor *Preprocessor) expand(statements []Statement, depth int) ([]Statement, error) {
+	var result, expansion []Statement
+	var conditionals []conditional
+	var err error
+
+	isActive := func() bool {
+		return len(conditionals) == 0 || conditionals[len(conditionals)-1].isActive
+	}
+
+	for i := 0; i < len(statements); i++ {
+		current := statements[i]
+		label, statement := splitLabel(current.Text)
+		directive, operands := splitDirective(statement)
+		lowerDirective := strings.ToLower(directive)
+
+		switch lowerDirective {
+
+		case ".if", ".ifdef", ".ifndef":
+			condition := false
+			if isActive() {
+				if lowerDirective == ".if" {
+					value, err := Evaluate(operands, preprocessor.Symbols)
+					if err != nil {
+						return nil, locate(current, err)
+					}
+					condition = value != 0
+				} else {
+					_, isDefined := preprocessor.Symbols[operands]
+					condition = isDefined == (lowerDirective == ".ifdef")
+				}
+			}
+			conditionals = append(conditionals, conditional{isActive: isActive() && condition, parentActive: isActive(), opening: current})
+			continue
+
+		case ".else":
+			if len(conditionals) == 0 || conditionals[len(conditionals)-1].hasElse {
+				return nil, locate(current, errors.New("Unexpected .else in "+current.Text))
+			}
+			top := &conditionals[len(conditionals)-1]
+			top.isActive = top.parentActive && !top.isActive
+			top.hasElse = true
+			continue
+
+		case ".endif":
+			if len(conditionals) == 0 {
+				return nil, locate(current, errors.New("Unexpected .endif without matching .if"))
+			}
+			conditionals = conditionals[:len(conditionals)-1]
+			continue
+		}
+
+		if !isActive() {
+			continue
+		}
+
+		switch lowerDirective {
+
+		case ".macro":
+			body, end, err := collectBlock(statements, i, ".macro", ".endm")
+			if err != nil {
+				return nil, err
+			}
+			err = preprocessor.defineMacro(operands, body)
+			if err != nil {
+				return nil, locate(current, err)
+			}
+			i = end
+			expansion = nil
+
+		case ".endm", ".endr":
+			return nil, locate(current, errors.New("Unexpected "+directive+" without matching block"))
+
+		case ".rept":
+			body, end, err := collectBlock(statements, i, ".rept", ".endr")
+			if err != nil {
+				return nil, err
+			}
+			count, err := Evaluate(operands, preprocessor.Symbols)
+			if err != nil {
+				return nil, locate(current, err)
+			}
+			if count < 0 {
+				return nil, locate(current, errors.New("Negative repeat count in "+current.Text))
+			}
+			if count > MAX_REPEAT_STATEMENTS {
+				return nil, locate(current, errors.New("Repeat count "+strconv.FormatInt(count, 10)+" is above the limit of "+strconv.Itoa(MAX_REPEAT_STATEMENTS)+" in "+current.Text))
+			}
+			expansion = nil
+			for j := int64(0); j < count; j++ {
+				repetition, err := preprocessor.expand(body, depth)
+				if err != nil {
+					return nil, err
+				}
+				expansion = append(expansion, repetition...)
+				if len(expansion) > MAX_REPEAT_STATEMENTS {
+					return nil, locate(current, errors.New("Repeated block expands to more than "+strconv.Itoa(MAX_REPEAT_STATEMENTS)+" statements in "+current.Text))
+				}
+			}
+			i = end
+
+		case ".equ", ".set":
+			err = preprocessor.defineSymbol(lowerDirective, operands)
+			if err != nil {
+				return nil, locate(current, err)
+			}
+			expansion = nil
+
+		case ".include":
+			expansion, err = preprocessor.include(current, operands)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			currentMacro, isMacro := preprocessor.macros[directive]
+			if !isMacro {
+				result = append(result, current)
+				continue
+			}
+			if depth >= MAX_MACRO_DEPTH {
+				return nil, locate(current, errors.New("Macro expansion too deep (recursive macro?) in "+current.Text))
+			}
+			expansion, err = preprocessor.invokeMacro(currentMacro, operands, current.Location)
+			if err != nil {
+				return nil, locate(current, err)
+			}
+			expansion, err = preprocessor.expand(expansion, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result = append(result, attachLabel(label, current.Location, expansion)...)
+	}
+
+	if len(conditionals) != 0 {
+		opening := conditionals[len(conditionals)-1].opening
+		return nil, locate(opening, errors.New("Missing .endif for "+opening.Text))
+	}
+	return result, nil
+}
+
+// Method to read and expand a file named by an .include directive.
+// The file name is resolved relative to the directory of the including file.
+func (preprocessor *Preprocessor) include(current Statement, operands string) ([]Statement, error) {
+	fileName := strings.Trim(operands, "\"")
+	if fileName == "" {
+		return nil, locate(current, errors.New("Missing file name in "+current.Text))
+	}
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(filepath.Dir(current.Location.File), fileName)
+	}
+
+	absolutePath, err := filepath.Abs(fileName)
+	if err != nil {
+		return nil, locate(current, err)
+	}
+	for i, included := range preprocessor.includes {
+		if included == absolutePath {
+			cycle := append(append([]string{}, preprocessor.includes[i:]...), absolutePath)
+			return nil, locate(current, errors.New("Include cycle detected : "+strings.Join(cycle, " -> ")))
+		}
+	}
+
+	statements, err := ReadFile(fileName)
+	if err != nil {
+		return nil, locate(current, err)
+	}
+
+	preprocessor.includes = append(preprocessor.includes, absolutePath)
+	expansion, err := preprocessor.expand(statements, 0)
+	preprocessor.includes = preprocessor.includes[:len(preprocessor.includes)-1]
+	return expansion, err
+}
+
+// Method to handle .equ and .set directives.
+// Symbols defined with .equ cannot be redefined.
+func (preprocessor *Preprocessor) defineSymbol(directive, operands string) error {
+	indexComma := strings.Index(operands, ",")
+	if indexComma == -1 {
+		return errors.New("Syntax error occurred in " + directive + " " + operands)
+	}
+	name := strings.TrimSpace(operands[:indexComma])
+	if !symbolRegex.MatchString(name) {
+		return errors.New("Invalid symbol name " + name + " in " + directive + " " + operands)
+	}
+	if _, isDefined := preprocessor.Symbols[name]; isDefined && directive == ".equ" {
+		return errors.New("Symbol " + name + " is already defined in " + directive + " " + operands)
+	}
+	value, err := Evaluate(operands[indexComma+1:], preprocessor.Symbols)
+	if err != nil {
+		return err
+	}
+	preprocessor.Symbols[name] = value
+	return nil
+}
+
+// Method to register a macro from its .macro operands and body.
+// The header ends at its semicolon, so a line break in it means the semicolon was forgotten.
+func (preprocessor *Preprocessor) defineMacro(operands string, body []Statement) error {
+	if indexNewline := strings.IndexAny(operands, "\r\n"); indexNewline != -1 {
+		return errors.New("Missing semicolon after .macro " + operands[:indexNewline])
+	}
+	fields := strings.FieldsFunc(operands, func(char rune) bool {
+		return char == ',' || char == ' ' || char == '\t'
+	})
+	if len(fields) == 0 {
+		return errors.New("Missing macro name in .macro")
+	}
+	if !symbolRegex.MatchString(fields[0]) {
+		return errors.New("Invalid macro name " + fields[0] + " in .macro " + operands)
+	}
+
+	newMacro := macro{name: fields[0], defaults: make(map[string]string), body: body}
+	for _, parameter := range fields[1:] {
+		indexEquals := strings.Index(parameter, "=")
+		if indexEquals != -1 {
+			newMacro.defaults[parameter[:indexEquals]] = parameter[indexEquals+1:]
+			parameter = parameter[:indexEquals]
+		}
+		if !symbolRegex.MatchString(parameter) {
+			return errors.New("Invalid parameter name " + parameter + " in .macro " + operands)
+		}
+		newMacro.parameters = append(newMacro.parameters, parameter)
+	}
+	preprocessor.macros[newMacro.name] = &newMacro
+	return nil
+}
+
+// Function to split macro arguments on top-level commas.
+func splitArguments(operands string) []string {
+	var arguments []string
+	var depth, start int
+	if strings.TrimSpace(operands) == "" {
+		return arguments
+	}
+	for i := 0; i < len(operands); i++ {
+		switch operands[i] {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				arguments = append(arguments, strings.TrimSpace(operands[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(arguments, strings.TrimSpace(operands[start:]))
+}
+
+// Method to produce the body of a macro with its parameters substituted.
+// Labels defined inside the body are renamed so that every invocation gets its own copy.
+// Expanded statements are reported at the location of the invocation.
+func (preprocessor *Preprocessor) invokeMacro(currentMacro *macro, operands string, location Location) ([]Statement, error) {
+	arguments := splitArguments(operands)
+	if len(arguments) > len(currentMacro.parameters) {
+		return nil, errors.New("Too many arguments for macro " + currentMacro.name + " in " + currentMacro.name + " " + operands)
+	}
+
+	values := make(map[string]string)
+	for i, parameter := range currentMacro.parameters {
+		if i < len(arguments) && arguments[i] != "" {
+			values[parameter] = arguments[i]
+		} else if value, hasDefault := currentMacro.defaults[parameter]; hasDefault {
+			values[parameter] = value
+		} else {
+			return nil, errors.New("Missing value for parameter " + parameter + " of macro " + currentMacro.name)
+		}
+	}
+
+	preprocessor.counter++
+	invocation := strconv.Itoa(preprocessor.counter)
+
+	// labels are renamed before the arguments are substituted, so that an argument naming an outer symbol is kept
+	localLabels := make(map[string]string)
+	for _, statement := range currentMacro.body {
+		if label, _ := splitLabel(statement.Text); label != "" {
+			localLabels[label] = label + "_" + invocation
+			preprocessor.localLabels[localLabels[label]] = localLabel{currentMacro.name, location}
+		}
+	}
+
+	var body []Statement
+	for _, statement := range currentMacro.body {
+		substituted, err := substituteParameters(renameSymbols(statement.Text, localLabels), values, invocation)
+		if err != nil {
+			return nil, errors.New(err.Error() + " of macro " + currentMacro.name)
+		}
+		body = append(body, Statement{Text: substituted, Location: location})
+	}
+	return body, nil
+}
+
+// Function to rename the symbols of a statement found in names.
+// Parameter references such as \name are left unchanged.
+func renameSymbols(statement string, names map[string]string) string {
+	var result strings.Builder
+	for i := 0; i < len(statement); {
+		if !isIdentifierChar(statement[i]) {
+			result.WriteByte(statement[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(statement) && isIdentifierChar(statement[end]) {
+			end++
+		}
+		word := statement[i:end]
+		isReference := i > 0 && statement[i-1] == '\\'
+		if newName, isRenamed := names[word]; isRenamed && !isReference {
+			word = newName
+		}
+		result.WriteString(word)
+		i = end
+	}
+	return result.String()
+}
+
+// Function to replace \parameter references in a statement.
+// \@ is replaced by the invocation number and \() is an empty separator.
+func substituteParameters(statement string, values map[string]string, invocation string) (string, error) {
+	var result strings.Builder
+	for i := 0; i < len(statement); i++ {
+		if statement[i] != '\\' || i+1 == len(statement) {
+			result.WriteByte(statement[i])
+			continue
+		}
+		if statement[i+1] == '@' {
+			result.WriteString(invocation)
+			i++
+			continue
+		}
+		if strings.HasPrefix(statement[i+1:], "()") {
+			i += 2
+			continue
+		}
+		end := i + 1
+		for end < len(statement) && isIdentifierChar(statement[end]) {
+			end++
+		}
+		value, isParameter := values[statement[i+1:end]]
+		if !isParameter {
+			return "", errors.New("Unknown parameter \\" + statement[i+1:end])
+		}
+		result.WriteString(value)
+		i = end - 1
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,107 @@
+package assembler
+
+import (
+	"strings"
+	"testing"
+)
+
+// Function to split source text into statements, as ReadFile does, at the file test.s.
+func statementsOf(text string) []Statement {
+	var statements []Statement
+	line := 1
+	for _, part := range strings.Split(text, ";") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed != "" {
+			start := line + strings.Count(part[:strings.Index(part, trimmed)], "\n")
+			statements = append(statements, Statement{Text: trimmed, Location: Location{"test.s", start}})
+		}
+		line += strings.Count(part, "\n")
+	}
+	return statements
+}
+
+// Function to join the text of expanded statements, one per line.
+func textOf(statements []Statement) string {
+	var lines []string
+	for _, statement := range statements {
+		lines = append(lines, statement.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"macro with parameters",
+			".macro add3 rd, rn, imm=3; ADDI \\rd, \\rn, #\\imm; .endm; add3 X1, X2; add3 X3, X4, 5;",
+			"ADDI X1, X2, #3\nADDI X3, X4, #5"},
+		{"invocation number and separator",
+			".macro tag; B l\\@\\()x; .endm; tag; tag;",
+			"B l1x\nB l2x"},
+		{"local labels renamed per invocation",
+			".macro spin; loop: SUBI X1, X1, #1; CBNZ X1, loop; .endm; spin; spin;",
+			"loop_1: SUBI X1, X1, #1\nCBNZ X1, loop_1\nloop_2: SUBI X1, X1, #1\nCBNZ X1, loop_2"},
+		{"argument naming an outer label",
+			".macro jump target; done: B \\target; .endm; jump done;",
+			"done_1: B done"},
+		{"label on an invocation",
+			".macro one; ADDI X1, X1, #1; .endm; start: one;",
+			"start: ADDI X1, X1, #1"},
+		{"rept",
+			".rept 3; ADDI X1, X1, #1; .endr;",
+			"ADDI X1, X1, #1\nADDI X1, X1, #1\nADDI X1, X1, #1"},
+		{"empty rept", ".rept 0; ADDI X1, X1, #1; .endr; B end;", "B end"},
+		{"conditional assembly",
+			".equ N, 2; .if N > 1; ADDI X1, X1, #1; .else; SUBI X1, X1, #1; .endif; .ifdef M; B x; .endif; .ifndef M; B y; .endif;",
+			"ADDI X1, X1, #1\nB y"},
+		{"set redefines", ".set N, 1; .set N, N + 1; .if N == 2; B two; .endif;", "B two"},
+	}
+	for _, test := range tests {
+		expansion, err := NewPreprocessor().Expand(statementsOf(test.source))
+		if err != nil {
+			t.Errorf("%s: Expand failed: %v", test.name, err)
+			continue
+		}
+		if got := textOf(expansion); got != test.want {
+			t.Errorf("%s: Expand = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"missing semicolon after the header", ".macro m\nloop: ADDI X1, X1, #1;\n.endm;\nm;", "test.s:1: Missing semicolon after .macro m"},
+		{"invalid parameter", ".macro m, a, 1b; .endm;", "test.s:1: Invalid parameter name 1b"},
+		{"invalid macro name", ".macro 2m; .endm;", "test.s:1: Invalid macro name 2m"},
+		{"operands after endm", ".macro m;\nB x;\n.endm\nm;", "test.s:3: Unexpected operands m after .endm"},
+		{"operands after endr", ".rept 2;\nB x;\n.endr\nB y;", "test.s:3: Unexpected operands B y after .endr"},
+		{"missing endm", ".macro m; B x;", "test.s:1: Missing .endm"},
+		{"unexpected endr", ".endr;", "test.s:1: Unexpected .endr"},
+		{"negative repeat count", ".rept -1; .endr;", "test.s:1: Negative repeat count"},
+		{"repeat count above the limit", ".rept 3000000000; B x; .endr;", "test.s:1: Repeat count 3000000000 is above the limit"},
+		{"nested repeats above the limit", ".rept 2000; .rept 2000; B x; .endr; .endr;", "test.s:1: Repeated block expands to more than"},
+		{"recursive macro", ".macro m; m; .endm; m;", "Macro expansion too deep"},
+		{"too many arguments", ".macro m a; .endm; m 1, 2;", "Too many arguments for macro m"},
+		{"missing argument", ".macro m a; .endm; m;", "Missing value for parameter a of macro m"},
+		{"unknown parameter", ".macro m a; B \\b; .endm; m 1;", "Unknown parameter \\b of macro m"},
+		{"label clashing with a macro label", ".macro m;\nloop: B loop;\n.endm;\nm;\nloop_1: B loop_1;", "test.s:4: Label loop_1 of macro m clashes with the label loop_1 at test.s:5"},
+		{"unexpected else", ".else;", "Unexpected .else"},
+		{"missing endif", ".if 1;\nB x;", "test.s:1: Missing .endif"},
+		{"redefined equ", ".equ N, 1; .equ N, 2;", "Symbol N is already defined"},
+	}
+	for _, test := range tests {
+		_, err := NewPreprocessor().Expand(statementsOf(test.source))
+		if err == nil {
+			t.Errorf("%s: Expand succeeded, want an error", test.name)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: Expand failed with %q, want %q", test.name, err.Error(), test.want)
+		}
+	}
+}
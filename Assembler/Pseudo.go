@@ -0,0 +1,64 @@
+package assembler
+
+// Function to expand a pseudo-instruction into the instructions it stands for.
+// Returns false if the instruction is not a pseudo-instruction.
+//
+//	MOV Xd, Xm     =>  ADD Xd, Xm, XZR
+//	MOV Xd, #imm   =>  MOVZ Xd, #imm, LSL 0
+//	CMP Xn, Xm     =>  SUBS XZR, Xn, Xm        (and #imm => SUBIS)
+//	CMN Xn, Xm     =>  ADDS XZR, Xn, Xm        (and #imm => ADDIS)
+//	TST Xn, Xm     =>  ANDS XZR, Xn, Xm        (and #imm => ANDIS)
+//	NEG Xd, Xm     =>  SUB Xd, XZR, Xm
+//	MVN Xd, Xm     =>  SUB Xd, XZR, Xm; SUBI Xd, Xd, #1
+//	LDA Xd, label  =>  MOVZ Xd, #label, LSL 0
+func expandPseudoInstruction(instruction *Instruction) ([]string, bool, error) {
+	var err error
+	switch instruction.Mnemonic {
+	case "MOV", "CMP", "CMN", "TST":
+		err = instruction.CheckOperands(REGISTER_OPERAND, REGISTER_OPERAND+" or "+IMMEDIATE_OPERAND)
+	case "NEG", "MVN":
+		err = instruction.CheckOperands(REGISTER_OPERAND, REGISTER_OPERAND)
+	case "LDA":
+		err = instruction.CheckOperands(REGISTER_OPERAND, LABEL_OPERAND)
+	default:
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+
+	first, second := instruction.Operands[0], instruction.Operands[1]
+	isImmediate := second.Kind == IMMEDIATE_OPERAND
+
+	switch instruction.Mnemonic {
+
+	case "MOV":
+		if isImmediate {
+			return []string{"MOVZ " + first.Text + ", #" + second.Expression + ", LSL 0"}, true, nil
+		}
+		return []string{"ADD " + first.Text + ", " + second.Text + ", XZR"}, true, nil
+
+	case "CMP", "CMN", "TST":
+		instructions := map[string][]string{
+			"CMP": {"SUBS", "SUBIS"},
+			"CMN": {"ADDS", "ADDIS"},
+			"TST": {"ANDS", "ANDIS"},
+		}[instruction.Mnemonic]
+		if isImmediate {
+			return []string{instructions[1] + " XZR, " + first.Text + ", #" + second.Expression}, true, nil
+		}
+		return []string{instructions[0] + " XZR, " + first.Text + ", " + second.Text}, true, nil
+
+	case "NEG":
+		return []string{"SUB " + first.Text + ", XZR, " + second.Text}, true, nil
+
+	case "MVN":
+		// bitwise not is -x - 1 in two's complement
+		return []string{"SUB " + first.Text + ", XZR, " + second.Text, "SUBI " + first.Text + ", " + first.Text + ", #1"}, true, nil
+
+	case "LDA":
+		return []string{"MOVZ " + first.Text + ", #" + second.Expression + ", LSL 0"}, true, nil
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,74 @@
+package assembler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Location identifies the line of a source file a statement was read from.
+type Location struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// String is a method to format a location as file:line.
+func (location Location) String() string {
+	return location.File + ":" + strconv.Itoa(location.Line)
+}
+
+// Statement is a single semicolon terminated statement of a program.
+// Alias holds the pseudo-instruction a statement was expanded from, if any.
+type Statement struct {
+	Text     string   `json:"text"`
+	Alias    string   `json:"alias,omitempty"`
+	Location Location `json:"location"`
+}
+
+// Function to prefix an error with the location of the statement that caused it.
+func locate(statement Statement, err error) error {
+	return errors.New(statement.Location.String() + ": " + err.Error())
+}
+
+// ReadFile is a function to split a source file into semicolon terminated statements.
+func ReadFile(fileName string) ([]Statement, error) {
+	var statements []Statement
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.New("Error opening file : " + err.Error())
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	lineNumber := 1
+
+	for {
+		line, err := reader.ReadString(';')
+		if err != nil && err != io.EOF {
+			return nil, errors.New("Error while reading file : " + err.Error())
+		}
+
+		// the statement starts at its first non-space character
+		text := strings.TrimSpace(strings.TrimRight(line, ";"))
+		start := lineNumber
+		if len(text) != 0 {
+			start += strings.Count(line[:strings.Index(line, text)], "\n")
+		}
+		lineNumber += strings.Count(line, "\n")
+
+		if err == io.EOF {
+			if len(text) != 0 {
+				return nil, errors.New(Location{fileName, start}.String() + ": Missing semicolon near : " + text)
+			}
+			break
+		}
+		if len(text) != 0 {
+			statements = append(statements, Statement{Text: text, Location: Location{fileName, start}})
+		}
+	}
+	return statements, nil
+}
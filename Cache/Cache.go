@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"math/rand"
+)
+
+// Struct to represent a block frame of a cache
+type line struct {
+	isValid  bool
+	isDirty  bool
+	tag      int64
+	lastUsed int64
+	loaded   int64
+}
+
+// Statistics counts the accesses made to a cache level.
+// Every miss is either compulsory, a capacity miss or a conflict miss.
+type Statistics struct {
+	Reads      int64
+	Writes     int64
+	Hits       int64
+	Misses     int64
+	Compulsory int64
+	Capacity   int64
+	Conflict   int64
+	WriteBacks int64
+}
+
+// Cache is a single cache level. Accesses it cannot serve go to the next level,
+// or to memory after the last level.
+type Cache struct {
+	Name   string
+	Config Config
+	Statistics
+	sets   [][]line
+	next   *Cache
+	time   int64
+	seen   map[int64]bool
+	shadow map[int64]int64
+	random *rand.Rand
+}
+
+// New is a function to create an empty cache level in front of the given next level.
+func New(name string, config Config, next *Cache) *Cache {
+	sets := make([][]line, config.Blocks()/config.Ways())
+	for i := range sets {
+		sets[i] = make([]line, config.Ways())
+	}
+	return &Cache{
+		Name:   name,
+		Config: config,
+		sets:   sets,
+		next:   next,
+		seen:   make(map[int64]bool),
+		shadow: make(map[int64]int64),
+		random: rand.New(rand.NewSource(1)),
+	}
+}
+
+// Access is a method to read or write the block holding a byte address.
+func (cache *Cache) Access(address int64, isWrite bool) {
+	cache.time++
+	if isWrite {
+		cache.Writes++
+	} else {
+		cache.Reads++
+	}
+
+	block := address / cache.Config.BlockSize
+	set := cache.sets[block%int64(len(cache.sets))]
+	tag := block / int64(len(cache.sets))
+	allocate := !isWrite || cache.Config.WriteAllocate
+	isShadowHit := cache.touchShadow(block, allocate)
+
+	for i := range set {
+		if set[i].isValid && set[i].tag == tag {
+			cache.Hits++
+			set[i].lastUsed = cache.time
+			if isWrite && cache.Config.WriteBack {
+				set[i].isDirty = true
+			} else if isWrite {
+				cache.forward(address, true)
+			}
+			return
+		}
+	}
+
+	cache.Misses++
+	if !cache.seen[block] {
+		cache.Compulsory++
+	} else if !isShadowHit {
+		cache.Capacity++
+	} else {
+		cache.Conflict++
+	}
+	cache.seen[block] = true
+
+	if !allocate {
+		cache.forward(address, true)
+		return
+	}
+
+	victim := &set[cache.victim(set)]
+	if victim.isValid && victim.isDirty {
+		cache.WriteBacks++
+		victimBlock := victim.tag*int64(len(cache.sets)) + block%int64(len(cache.sets))
+		cache.forward(victimBlock*cache.Config.BlockSize, true)
+	}
+	cache.forward(address, false)
+	*victim = line{isValid: true, tag: tag, lastUsed: cache.time, loaded: cache.time}
+
+	if isWrite && cache.Config.WriteBack {
+		victim.isDirty = true
+	} else if isWrite {
+		cache.forward(address, true)
+	}
+}
+
+// Method to pass an access on to the next level.
+func (cache *Cache) forward(address int64, isWrite bool) {
+	if cache.next != nil {
+		cache.next.Access(address, isWrite)
+	}
+}
+
+// Method to choose the block frame of a set that a new block replaces.
+// Empty frames are used first.
+func (cache *Cache) victim(set []line) int {
+	victim := 0
+	for i := range set {
+		if !set[i].isValid {
+			return i
+		}
+		switch cache.Config.Replacement {
+		case LRU:
+			if set[i].lastUsed < set[victim].lastUsed {
+				victim = i
+			}
+		case FIFO:
+			if set[i].loaded < set[victim].loaded {
+				victim = i
+			}
+		}
+	}
+	if cache.Config.Replacement == RANDOM {
+		victim = cache.random.Intn(len(set))
+	}
+	return victim
+}
+
+// Method to access a fully associative LRU cache of the same size, which tells
+// capacity misses from conflict misses. Returns true if the block was there.
+func (cache *Cache) touchShadow(block int64, allocate bool) bool {
+	_, isHit := cache.shadow[block]
+	if !isHit && !allocate {
+		return false
+	}
+	cache.shadow[block] = cache.time
+	if int64(len(cache.shadow)) > cache.Config.Blocks() {
+		var oldest int64 = -1
+		for candidate, lastUsed := range cache.shadow {
+			if oldest == -1 || lastUsed < cache.shadow[oldest] {
+				oldest = candidate
+			}
+		}
+		delete(cache.shadow, oldest)
+	}
+	return isHit
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	Memory "github.com/coderick14/ARMed/Memory"
+	"testing"
+)
+
+// Struct to describe one access made to a cache
+type access struct {
+	address int64
+	isWrite bool
+}
+
+// Function to list reads of the given byte addresses.
+func reads(addresses ...int64) []access {
+	var accesses []access
+	for _, address := range addresses {
+		accesses = append(accesses, access{address, false})
+	}
+	return accesses
+}
+
+// Function to create a cache from a specification, failing the test if it is invalid.
+func newCache(t *testing.T, spec string, next *Cache) *Cache {
+	config, err := ParseConfig(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New("L1", config, next)
+}
+
+func TestAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		accesses []access
+		want     Statistics
+	}{
+		{"hits in a block", "size=64,block=16", reads(0, 4, 12, 16),
+			Statistics{Reads: 4, Hits: 2, Misses: 2, Compulsory: 2}},
+		{"conflict miss", "size=64,block=16", reads(0, 64, 0),
+			Statistics{Reads: 3, Misses: 3, Compulsory: 2, Conflict: 1}},
+		{"no conflict with two ways", "size=64,block=16,ways=2", reads(0, 64, 0),
+			Statistics{Reads: 3, Hits: 1, Misses: 2, Compulsory: 2}},
+		{"capacity miss", "size=64,block=16,ways=full", reads(0, 16, 32, 48, 64, 0),
+			Statistics{Reads: 6, Misses: 6, Compulsory: 5, Capacity: 1}},
+		{"LRU replacement", "size=32,block=16,ways=2,replace=lru", reads(0, 16, 0, 32, 0),
+			Statistics{Reads: 5, Hits: 2, Misses: 3, Compulsory: 3}},
+		{"FIFO replacement", "size=32,block=16,ways=2,replace=fifo", reads(0, 16, 0, 32, 0),
+			Statistics{Reads: 5, Hits: 1, Misses: 4, Compulsory: 3, Conflict: 1}},
+		{"clean block is not written back", "size=64,block=16", reads(0, 64),
+			Statistics{Reads: 2, Misses: 2, Compulsory: 2}},
+	}
+	for _, test := range tests {
+		cache := newCache(t, test.spec, nil)
+		for _, current := range test.accesses {
+			cache.Access(current.address, current.isWrite)
+		}
+		if cache.Statistics != test.want {
+			t.Errorf("%s: statistics %+v, want %+v", test.name, cache.Statistics, test.want)
+		}
+	}
+}
+
+func TestWritePolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		accesses  []access
+		want      Statistics
+		wantLevel Statistics
+	}{
+		// the fill of block 0, the write back of block 0 and the fill of block 4
+		{"write-back write-allocate", "size=64,block=16", []access{{0, true}, {0, true}, {64, false}},
+			Statistics{Reads: 1, Writes: 2, Hits: 1, Misses: 2, Compulsory: 2, WriteBacks: 1},
+			Statistics{Reads: 2, Writes: 1, Hits: 1, Misses: 2, Compulsory: 2}},
+		// every write goes to the next level, and the first one does not allocate
+		{"write-through no-write-allocate", "size=64,block=16,write=through,allocate=no", []access{{0, true}, {0, false}, {0, true}},
+			Statistics{Reads: 1, Writes: 2, Hits: 1, Misses: 2, Compulsory: 1, Capacity: 1},
+			Statistics{Reads: 1, Writes: 2, Hits: 2, Misses: 1, Compulsory: 1}},
+	}
+	for _, test := range tests {
+		next := newCache(t, "size=1k,block=16", nil)
+		cache := newCache(t, test.spec, next)
+		for _, current := range test.accesses {
+			cache.Access(current.address, current.isWrite)
+		}
+		if cache.Statistics != test.want {
+			t.Errorf("%s: L1 statistics %+v, want %+v", test.name, cache.Statistics, test.want)
+		}
+		if next.Statistics != test.wantLevel {
+			t.Errorf("%s: L2 statistics %+v, want %+v", test.name, next.Statistics, test.wantLevel)
+		}
+	}
+}
+
+func TestHierarchy(t *testing.T) {
+	config, _ := ParseConfig("size=64,block=16")
+	instructions := NewHierarchy(true, []Config{config, config})
+	for PC := int64(0); PC < 8; PC++ {
+		instructions.Observe(&Memory.Execution{PC: PC})
+	}
+	// instructions are 4 bytes long, so 8 of them fill two blocks
+	first := instructions.Levels[0].Statistics
+	if first.Reads != 8 || first.Misses != 2 || instructions.Levels[1].Reads != 2 {
+		t.Errorf("L1 %+v and L2 %+v, want 8 reads, 2 misses and 2 reads of L2", first, instructions.Levels[1].Statistics)
+	}
+	if instructions.Levels[0].Name != "L1 instruction" || instructions.Levels[1].Name != "L2 instruction" {
+		t.Errorf("names %s and %s, want L1 instruction and L2 instruction", instructions.Levels[0].Name, instructions.Levels[1].Name)
+	}
+
+	data := NewHierarchy(false, []Config{config})
+	data.Observe(&Memory.Execution{MemoryAccesses: []Memory.MemoryAccess{{Address: 32}, {Address: 36, IsWrite: true}}})
+	if statistics := data.Levels[0].Statistics; statistics.Reads != 1 || statistics.Writes != 1 || statistics.Hits != 1 {
+		t.Errorf("data statistics %+v, want a read miss and a write hit", statistics)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig("size=2K, block=32, ways=full, replace=FIFO, write=through, allocate=no")
+	want := Config{Size: 2048, BlockSize: 32, Associativity: 0, Replacement: FIFO, WriteBack: false, WriteAllocate: false}
+	if err != nil || config != want {
+		t.Errorf("ParseConfig = %+v, %v, want %+v", config, err, want)
+	}
+	if config.Ways() != 64 {
+		t.Errorf("Ways = %d, want 64", config.Ways())
+	}
+	if got, want := config.String(), "2048 bytes, 32 byte blocks, fully associative, FIFO, write-through, no-write-allocate"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+	if config, err = ParseConfig(""); err != nil || config != DefaultConfig {
+		t.Errorf("ParseConfig of an empty specification = %+v, %v, want the default", config, err)
+	}
+
+	errorTests := []string{
+		"size=1000",
+		"block=2",
+		"size=64,block=128",
+		"ways=3",
+		"size=64,block=16,ways=8",
+		"replace=mru",
+		"write=around",
+		"allocate=maybe",
+		"color=blue",
+		"size",
+		"size=1M",
+	}
+	for _, spec := range errorTests {
+		if _, err := ParseConfig(spec); err == nil {
+			t.Errorf("ParseConfig(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Replacement policies
+const (
+	LRU    = "lru"
+	FIFO   = "fifo"
+	RANDOM = "random"
+)
+
+// Config describes a single cache level.
+// An associativity of 0 means the cache is fully associative.
+type Config struct {
+	Size          int64
+	BlockSize     int64
+	Associativity int64
+	Replacement   string
+	WriteBack     bool
+	WriteAllocate bool
+}
+
+// DefaultConfig is used for every setting a cache specification leaves out
+var DefaultConfig = Config{
+	Size:          1024,
+	BlockSize:     16,
+	Associativity: 1,
+	Replacement:   LRU,
+	WriteBack:     true,
+	WriteAllocate: true,
+}
+
+// ParseConfig is a function to read a cache specification such as
+// "size=1024,block=16,ways=2,replace=lru,write=back,allocate=yes".
+func ParseConfig(spec string) (Config, error) {
+	config := DefaultConfig
+	for _, setting := range strings.Split(spec, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 {
+			return config, errors.New("Expected key=value, found " + setting + " in cache specification " + spec)
+		}
+		key, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.ToLower(strings.TrimSpace(parts[1]))
+
+		var err error
+		switch key {
+		case "size":
+			config.Size, err = parseSize(value)
+		case "block":
+			config.BlockSize, err = parseSize(value)
+		case "ways":
+			if value == "full" {
+				config.Associativity = 0
+			} else {
+				config.Associativity, err = strconv.ParseInt(value, 10, 64)
+			}
+		case "replace":
+			if value != LRU && value != FIFO && value != RANDOM {
+				err = errors.New("Unknown replacement policy " + value)
+			}
+			config.Replacement = value
+		case "write":
+			if value != "back" && value != "through" {
+				err = errors.New("Unknown write policy " + value)
+			}
+			config.WriteBack = value == "back"
+		case "allocate":
+			if value != "yes" && value != "no" {
+				err = errors.New("Expected yes or no, found " + value)
+			}
+			config.WriteAllocate = value == "yes"
+		default:
+			err = errors.New("Unknown setting " + key)
+		}
+		if err != nil {
+			return config, errors.New(err.Error() + " in cache specification " + spec)
+		}
+	}
+	return config, config.check()
+}
+
+// Function to parse a size in bytes, optionally ending in K.
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	if strings.HasSuffix(value, "k") {
+		multiplier, value = 1024, strings.TrimSuffix(value, "k")
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("Invalid size " + value)
+	}
+	return size * multiplier, nil
+}
+
+// Function to check if a value is a power of two.
+func isPowerOfTwo(value int64) bool {
+	return value > 0 && value&(value-1) == 0
+}
+
+// Method to check that the settings describe a cache that can be built.
+func (config Config) check() error {
+	if !isPowerOfTwo(config.Size) || !isPowerOfTwo(config.BlockSize) {
+		return errors.New("Cache size and block size must be powers of two")
+	}
+	if config.BlockSize < 4 || config.BlockSize > config.Size {
+		return errors.New("Block size must be between 4 bytes and the cache size")
+	}
+	if config.Associativity != 0 && (!isPowerOfTwo(config.Associativity) || config.Associativity > config.Blocks()) {
+		return errors.New("Associativity must be a power of two no larger than the number of blocks")
+	}
+	return nil
+}
+
+// Blocks is a method to return the number of blocks a cache holds.
+func (config Config) Blocks() int64 {
+	return config.Size / config.BlockSize
+}
+
+// Ways is a method to return the number of blocks in a set.
+func (config Config) Ways() int64 {
+	if config.Associativity == 0 {
+		return config.Blocks()
+	}
+	return config.Associativity
+}
+
+// String is a method to describe a cache configuration, e.g.
+// "1024 bytes, 16 byte blocks, 2-way, LRU, write-back, write-allocate".
+func (config Config) String() string {
+	ways := strconv.FormatInt(config.Associativity, 10) + "-way"
+	if config.Associativity == 0 {
+		ways = "fully associative"
+	} else if config.Associativity == 1 {
+		ways = "direct mapped"
+	}
+	write, allocate := "write-through", "no-write-allocate"
+	if config.WriteBack {
+		write = "write-back"
+	}
+	if config.WriteAllocate {
+		allocate = "write-allocate"
+	}
+	return strconv.FormatInt(config.Size, 10) + " bytes, " + strconv.FormatInt(config.BlockSize, 10) + " byte blocks, " +
+		ways + ", " + strings.ToUpper(config.Replacement) + ", " + write + ", " + allocate
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"fmt"
+	Memory "github.com/coderick14/ARMed/Memory"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"strconv"
+)
+
+// Hierarchy is a chain of cache levels in front of either instruction or data memory.
+type Hierarchy struct {
+	IsInstruction bool
+	Levels        []*Cache
+}
+
+// NewHierarchy is a function to create cache levels L1, L2, ... from the given configurations.
+func NewHierarchy(isInstruction bool, configs []Config) *Hierarchy {
+	kind := " data"
+	if isInstruction {
+		kind = " instruction"
+	}
+	hierarchy := Hierarchy{IsInstruction: isInstruction, Levels: make([]*Cache, len(configs))}
+	var next *Cache
+	for i := len(configs) - 1; i >= 0; i-- {
+		next = New("L"+strconv.Itoa(i+1)+kind, configs[i], next)
+		hierarchy.Levels[i] = next
+	}
+	return &hierarchy
+}
+
+// Observe is a method to pass the instruction fetch or the data accesses of an instruction to the first level.
+// Instructions are 4 bytes long, so the instruction at PC is fetched from address 4 * PC.
+func (hierarchy *Hierarchy) Observe(execution *Memory.Execution) {
+	if len(hierarchy.Levels) == 0 {
+		return
+	}
+	if hierarchy.IsInstruction {
+		hierarchy.Levels[0].Access(execution.PC*Memory.WORD_SIZE, false)
+		return
+	}
+	for _, access := range execution.MemoryAccesses {
+		hierarchy.Levels[0].Access(access.Address, access.IsWrite)
+	}
+}
+
+// ShowStatistics is a method to pretty print the configuration, hits and misses of every level.
+func (hierarchy *Hierarchy) ShowStatistics() {
+	for _, level := range hierarchy.Levels {
+		fmt.Println(level.Name, ":", level.Config)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Cache", "Reads", "Writes", "Hits", "Misses", "Hit rate", "Compulsory", "Capacity", "Conflict", "Write backs"})
+	for _, level := range hierarchy.Levels {
+		hitRate := "-"
+		if accesses := level.Reads + level.Writes; accesses != 0 {
+			hitRate = strconv.FormatFloat(100*float64(level.Hits)/float64(accesses), 'f', 2, 64) + "%"
+		}
+		table.Append([]string{
+			level.Name,
+			strconv.FormatInt(level.Reads, 10),
+			strconv.FormatInt(level.Writes, 10),
+			strconv.FormatInt(level.Hits, 10),
+			strconv.FormatInt(level.Misses, 10),
+			hitRate,
+			strconv.FormatInt(level.Compulsory, 10),
+			strconv.FormatInt(level.Capacity, 10),
+			strconv.FormatInt(level.Conflict, 10),
+			strconv.FormatInt(level.WriteBacks, 10),
+		})
+	}
+	table.Render()
+	fmt.Printf("\n")
+}
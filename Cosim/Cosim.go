@@ -0,0 +1,195 @@
+package cosim
+
+import (
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Memory "github.com/coderick14/ARMed/Memory"
+	color "github.com/fatih/color"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"strconv"
+)
+
+// DEFAULT_MAX_INSTRUCTIONS is the number of instructions after which a run is stopped, unless told otherwise
+const DEFAULT_MAX_INSTRUCTIONS = 1000000
+
+// What is shown in place of a write once the program or the trace has ended
+const (
+	END_OF_PROGRAM = "end of program"
+	END_OF_TRACE   = "end of trace"
+)
+
+// Divergence is the first write on which ARMed and the hardware disagree.
+// Expected is the write made by ARMed and Actual the one found in the trace.
+type Divergence struct {
+	Instructions int64
+	PC           int64
+	Instruction  string
+	Location     string
+	What         string
+	Expected     string
+	Actual       string
+	Where        string
+}
+
+// Result is the outcome of checking a trace. Error is set if ARMed could not run the program to its end.
+type Result struct {
+	Instructions   int64
+	RegisterWrites int
+	MemoryWrites   int
+	Divergence     *Divergence
+	Error          error
+}
+
+// Struct to hold the state of a trace being checked.
+// Return addresses are instruction numbers in ARMed and byte addresses in hardware, so the registers
+// and words holding one are followed from the BL that wrote it through the moves, stores and loads that copy it.
+type checker struct {
+	events    []Event
+	next      int
+	addresses *Memory.ReturnAddresses
+	result    Result
+}
+
+// Run is a function to run a linked program and check each register and memory write it makes
+// against the next write of a hardware trace, stopping at the first one that differs.
+func Run(program *Assembler.Object, events []Event, maxInstructions int64) *Result {
+	checker := checker{events: events, addresses: Memory.NewReturnAddresses()}
+	result := &checker.result
+	Memory.ResetMachine()
+	err := Memory.LoadProgram(program)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	Memory.InitRegisters()
+
+	for Memory.IsValidPC(Memory.InstructionMem.PC) {
+		if result.Instructions == maxInstructions {
+			result.Error = errors.New("Instruction limit of " + strconv.FormatInt(maxInstructions, 10) + " reached")
+			return result
+		}
+		PC := Memory.InstructionMem.PC
+		location := Memory.InstructionMem.Locations[PC]
+		Memory.LastExecution = Memory.Execution{}
+		err = Memory.InstructionMem.ValidateAndExecuteInstruction()
+		result.Instructions++
+		if err != nil {
+			result.Error = errors.New(location.String() + ": " + err.Error())
+			return result
+		}
+		checker.check(PC)
+		if result.Divergence != nil {
+			return result
+		}
+	}
+
+	if checker.next < len(events) {
+		checker.diverge(Memory.InstructionMem.PC, "write", END_OF_PROGRAM, events[checker.next].String(), events[checker.next].Where)
+	}
+	return result
+}
+
+// Method to compare the writes of the instruction just executed with the trace.
+func (checker *checker) check(PC int64) {
+	execution := &Memory.LastExecution
+	checker.addresses.Observe(execution)
+	for _, write := range execution.RegistersWritten {
+		value := checker.addresses.RegisterInHardware(write.Register, uint64(write.NewValue))
+		if !checker.compare(PC, Event{Register: write.Register, Value: value}, ^uint64(0)) {
+			return
+		}
+		checker.result.RegisterWrites++
+	}
+
+	for _, access := range execution.MemoryAccesses {
+		if !access.IsWrite {
+			continue
+		}
+		mask := uint64(1)<<uint(8*access.Size) - 1
+		value := checker.addresses.WordInHardware(access.Address, uint64(access.Value)) & mask
+		if !checker.compare(PC, Event{IsMemory: true, Address: access.Address, Value: value}, mask) {
+			return
+		}
+		checker.result.MemoryWrites++
+	}
+}
+
+// Method to compare a write of ARMed with the next write of the trace, whose value is compared on the bits of mask.
+// Returns false, after recording the divergence, if they differ.
+func (checker *checker) compare(PC int64, expected Event, mask uint64) bool {
+	if checker.next == len(checker.events) {
+		checker.diverge(PC, "write", expected.String(), END_OF_TRACE, "")
+		return false
+	}
+	actual := checker.events[checker.next]
+	checker.next++
+
+	isSameTarget := actual.IsMemory == expected.IsMemory && actual.Register == expected.Register && actual.Address == expected.Address
+	if !isSameTarget {
+		checker.diverge(PC, "write", expected.String(), actual.String(), actual.Where)
+		return false
+	}
+	if actual.IsUnknown || actual.Value&mask != expected.Value {
+		what := Memory.RegisterName(int(expected.Register))
+		if expected.IsMemory {
+			what = fmt.Sprintf("[0x%X]", expected.Address)
+		}
+		value := strconv.FormatInt(int64(actual.Value&mask), 10)
+		if actual.IsUnknown {
+			value = "x"
+		}
+		checker.diverge(PC, what, strconv.FormatInt(int64(expected.Value), 10), value, actual.Where)
+		return false
+	}
+	return true
+}
+
+// Method to record where ARMed and the trace first disagree.
+func (checker *checker) diverge(PC int64, what string, expected string, actual string, where string) {
+	divergence := Divergence{
+		Instructions: checker.result.Instructions,
+		PC:           PC,
+		What:         what,
+		Expected:     expected,
+		Actual:       actual,
+		Where:        where,
+	}
+	if Memory.IsValidPC(PC) {
+		divergence.Instruction = Memory.InstructionMem.Describe(PC)
+		divergence.Location = Memory.InstructionMem.Locations[PC].String()
+	}
+	checker.result.Divergence = &divergence
+}
+
+// ShowResult is a function to print whether the trace agrees with ARMed, or the first write that differs.
+// Returns an error if they differ or the program could not run to its end.
+func ShowResult(result *Result) error {
+	if result.Error != nil {
+		fmt.Println(color.RedString("ERROR"), "after", result.Instructions, "instructions")
+		return result.Error
+	}
+	divergence := result.Divergence
+	if divergence == nil {
+		fmt.Println(color.GreenString("MATCH"), result.Instructions, "instructions,", result.RegisterWrites, "register writes and",
+			result.MemoryWrites, "memory writes agree with the trace")
+		return nil
+	}
+
+	if divergence.Instruction != "" {
+		fmt.Printf("%s at instruction %d, PC 0x%X, %s: %s\n", color.RedString("DIVERGED"), divergence.Instructions,
+			divergence.PC*Memory.WORD_SIZE, divergence.Location, divergence.Instruction)
+	} else {
+		fmt.Printf("%s after the last instruction, %d instructions in\n", color.RedString("DIVERGED"), divergence.Instructions)
+	}
+	if divergence.Where != "" {
+		fmt.Println("Trace :", divergence.Where)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Write", "ARMed", "Trace"})
+	table.Append([]string{divergence.What, divergence.Expected, divergence.Actual})
+	table.Render()
+	fmt.Printf("\n")
+	return errors.New("Trace diverged after " + strconv.Itoa(result.RegisterWrites+result.MemoryWrites) + " matching writes")
+}
@@ -0,0 +1,140 @@
+package cosim
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Event is a write to a register or to data memory made by the hardware.
+// Where tells where the event was found, as a line of a text trace or a time of a VCD file.
+type Event struct {
+	IsMemory  bool
+	Register  uint
+	Address   int64
+	Value     uint64
+	IsUnknown bool
+	Where     string
+}
+
+// String is a method to write an event the way it is written in a text trace.
+func (event Event) String() string {
+	value := strconv.FormatInt(int64(event.Value), 10)
+	if event.IsUnknown {
+		value = "x"
+	}
+	if event.IsMemory {
+		return fmt.Sprintf("[0x%X] = %s", event.Address, value)
+	}
+	return Memory.RegisterName(int(event.Register)) + " = " + value
+}
+
+// ReadTrace is a function to read the writes of a hardware simulation, from a VCD file if its
+// extension is .vcd and from a text trace otherwise. Signals name the signals of a VCD file.
+func ReadTrace(fileName string, signals Signals) ([]Event, error) {
+	if strings.ToLower(filepath.Ext(fileName)) == ".vcd" {
+		return ReadVCD(fileName, signals)
+	}
+	return ReadText(fileName)
+}
+
+// ReadText is a function to read a text trace, with one write on each line:
+//
+//	X1 = 6              a write to a register
+//	[0x3FF8] = 0x2A     a write to data memory, at a byte address
+//	@150 X1 = 6         the same, at simulation time 150
+//
+// Everything after # or // is a comment. Writes to XZR or X31 are ignored.
+func ReadText(fileName string) ([]Event, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.New("Error opening file : " + err.Error())
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, comment := range []string{"#", "//"} {
+			if index := strings.Index(text, comment); index >= 0 {
+				text = text[:index]
+			}
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		where := fileName + ":" + strconv.Itoa(line)
+		if strings.HasPrefix(text, "@") {
+			fields := strings.SplitN(text, " ", 2)
+			where += " at time " + fields[0][1:]
+			text = ""
+			if len(fields) == 2 {
+				text = strings.TrimSpace(fields[1])
+			}
+		}
+
+		event, err := parseWrite(text)
+		if err != nil {
+			return nil, errors.New(where + ": " + err.Error())
+		}
+		if event.IsMemory || event.Register != Memory.XZR {
+			event.Where = where
+			events = append(events, event)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.New("Error reading file : " + err.Error())
+	}
+	return events, nil
+}
+
+// Function to parse a write such as "X1 = 6" or "[0x3FF8] = 0x2A".
+func parseWrite(text string) (Event, error) {
+	parts := strings.SplitN(text, "=", 2)
+	if len(parts) != 2 {
+		return Event{}, errors.New("Expected REGISTER = VALUE or [ADDRESS] = VALUE, found " + text)
+	}
+	target := strings.TrimSpace(parts[0])
+	value, err := parseValue(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Event{}, err
+	}
+
+	if strings.HasPrefix(target, "[") && strings.HasSuffix(target, "]") {
+		address, err := Assembler.Evaluate(target[1:len(target)-1], nil)
+		if err != nil {
+			return Event{}, errors.New(err.Error() + " in address " + target)
+		}
+		return Event{IsMemory: true, Address: address, Value: value}, nil
+	}
+	if strings.ToUpper(target) == "X31" {
+		return Event{Register: Memory.XZR, Value: value}, nil
+	}
+	register, isRegister := Assembler.RegisterNumber(target)
+	if !isRegister {
+		return Event{}, errors.New("Unknown register " + target)
+	}
+	return Event{Register: register, Value: value}, nil
+}
+
+// Function to parse a value written in decimal, with or without a sign, or in hex or binary with 0x or 0b.
+// Values are 64 bits, so both 0xFFFFFFFFFFFFFFFF and -1 stand for all ones.
+func parseValue(text string) (uint64, error) {
+	value, err := strconv.ParseUint(text, 0, 64)
+	if err == nil {
+		return value, nil
+	}
+	signed, err := strconv.ParseInt(text, 0, 64)
+	if err != nil {
+		return 0, errors.New("Invalid value " + text)
+	}
+	return uint64(signed), nil
+}
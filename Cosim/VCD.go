@@ -0,0 +1,243 @@
+package cosim
+
+import (
+	"bufio"
+	"errors"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"os"
+	"strings"
+)
+
+// Roles of the signals read from a VCD file
+const (
+	CLOCK_SIGNAL       = "clock"
+	RESET_SIGNAL       = "reset"
+	REG_WRITE_SIGNAL   = "reg_write"
+	REG_SIGNAL         = "reg"
+	REG_DATA_SIGNAL    = "reg_data"
+	MEM_WRITE_SIGNAL   = "mem_write"
+	MEM_ADDRESS_SIGNAL = "mem_address"
+	MEM_DATA_SIGNAL    = "mem_data"
+)
+
+// Signals maps each role to the name of a signal in a VCD file.
+// A name may be the full hierarchical name, such as tb.cpu.RegWrite, or only the last part.
+type Signals map[string]string
+
+// DefaultSignals is a function to return the signal names of the single-cycle datapath of the textbook.
+// These are also the names written by ARMed with --vcd.
+func DefaultSignals() Signals {
+	return Signals{
+		CLOCK_SIGNAL:       "clk",
+		RESET_SIGNAL:       "reset",
+		REG_WRITE_SIGNAL:   "RegWrite",
+		REG_SIGNAL:         "WriteRegister",
+		REG_DATA_SIGNAL:    "WriteData",
+		MEM_WRITE_SIGNAL:   "MemWrite",
+		MEM_ADDRESS_SIGNAL: "MemAddress",
+		MEM_DATA_SIGNAL:    "MemWriteData",
+	}
+}
+
+// ParseSignals is a function to change the default signal names with a list like clock=clk,reg_write=rf_we.
+func ParseSignals(spec string) (Signals, error) {
+	signals := DefaultSignals()
+	if spec == "" {
+		return signals, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		keyValue := strings.SplitN(part, "=", 2)
+		role := strings.TrimSpace(keyValue[0])
+		if _, isKnown := signals[role]; !isKnown || len(keyValue) != 2 || strings.TrimSpace(keyValue[1]) == "" {
+			return nil, errors.New("Invalid signal " + part + ", expected ROLE=NAME with ROLE one of clock, reset, " +
+				"reg_write, reg, reg_data, mem_write, mem_address or mem_data")
+		}
+		signals[role] = strings.TrimSpace(keyValue[1])
+	}
+	return signals, nil
+}
+
+// Struct to describe a variable declared in a VCD file
+type vcdVariable struct {
+	name string
+	id   string
+}
+
+// Struct to hold the value of a signal, as a number and whether any of its bits is x or z
+type vcdValue struct {
+	value     uint64
+	isUnknown bool
+}
+
+// ReadVCD is a function to read the writes of a hardware simulation from a Value Change Dump.
+// The write signals are sampled just before each rising edge of the clock, as the registers and memory see them.
+// An edge writes a register if reg_write is 1 and memory if mem_write is 1, and edges while reset is 1 are skipped.
+// The reset signal may be missing from the file, every other one is needed.
+func ReadVCD(fileName string, signals Signals) ([]Event, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.New("Error opening file : " + err.Error())
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	scanner.Split(bufio.ScanWords)
+
+	// the header declares the variables, up to $enddefinitions
+	var variables []vcdVariable
+	var scopes []string
+	for {
+		if !scanner.Scan() {
+			return nil, errors.New(fileName + ": Missing $enddefinitions")
+		}
+		keyword := scanner.Text()
+		if keyword == "$enddefinitions" {
+			skipToEnd(scanner)
+			break
+		}
+		fields := skipToEnd(scanner)
+		switch keyword {
+		case "$scope":
+			if len(fields) >= 2 {
+				scopes = append(scopes, fields[1])
+			}
+		case "$upscope":
+			if len(scopes) != 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case "$var":
+			// $var TYPE SIZE ID NAME [RANGE] $end
+			if len(fields) < 4 {
+				return nil, errors.New(fileName + ": Invalid $var " + strings.Join(fields, " "))
+			}
+			name := strings.Join(append(append([]string{}, scopes...), fields[3]), ".")
+			variables = append(variables, vcdVariable{name, fields[2]})
+		}
+	}
+
+	ids := make(map[string]string)
+	for _, role := range []string{CLOCK_SIGNAL, RESET_SIGNAL, REG_WRITE_SIGNAL, REG_SIGNAL, REG_DATA_SIGNAL, MEM_WRITE_SIGNAL, MEM_ADDRESS_SIGNAL, MEM_DATA_SIGNAL} {
+		id, err := findVariable(variables, signals[role])
+		if err != nil {
+			if role == RESET_SIGNAL && signals[role] == DefaultSignals()[RESET_SIGNAL] {
+				continue
+			}
+			return nil, errors.New(fileName + ": " + err.Error() + " for " + role)
+		}
+		ids[role] = id
+	}
+
+	// values at the end of the last time step, and the changes made in the current one
+	values := make(map[string]vcdValue)
+	changes := make(map[string]vcdValue)
+	var events []Event
+	time := "0"
+	endStep := func() {
+		clock, isChanged := changes[ids[CLOCK_SIGNAL]]
+		previous, wasDumped := values[ids[CLOCK_SIGNAL]]
+		wasLow := wasDumped && !previous.isUnknown && previous.value == 0
+		isRising := isChanged && wasLow && !clock.isUnknown && clock.value == 1
+		if isRising && !isSet(values, ids[RESET_SIGNAL]) {
+			where := fileName + " at time " + time
+			if isSet(values, ids[REG_WRITE_SIGNAL]) {
+				register := values[ids[REG_SIGNAL]]
+				if register.value != Memory.XZR || register.isUnknown {
+					data := values[ids[REG_DATA_SIGNAL]]
+					events = append(events, Event{Register: uint(register.value & 31), Value: data.value, IsUnknown: data.isUnknown || register.isUnknown, Where: where})
+				}
+			}
+			if isSet(values, ids[MEM_WRITE_SIGNAL]) {
+				address, data := values[ids[MEM_ADDRESS_SIGNAL]], values[ids[MEM_DATA_SIGNAL]]
+				events = append(events, Event{IsMemory: true, Address: int64(address.value), Value: data.value, IsUnknown: data.isUnknown || address.isUnknown, Where: where})
+			}
+		}
+		for id, value := range changes {
+			values[id] = value
+			delete(changes, id)
+		}
+	}
+
+	for scanner.Scan() {
+		token := scanner.Text()
+		switch {
+		case strings.HasPrefix(token, "#"):
+			endStep()
+			time = token[1:]
+		case token[0] == 'b' || token[0] == 'B' || token[0] == 'r' || token[0] == 'R':
+			if !scanner.Scan() {
+				return nil, errors.New(fileName + ": Missing signal of value " + token)
+			}
+			if token[0] == 'b' || token[0] == 'B' {
+				changes[scanner.Text()] = parseBits(token[1:])
+			}
+		case token[0] == '0' || token[0] == '1' || token[0] == 'x' || token[0] == 'X' || token[0] == 'z' || token[0] == 'Z':
+			changes[token[1:]] = parseBits(token[:1])
+		case token == "$comment":
+			skipToEnd(scanner)
+		}
+		// $dumpvars, $dumpall, $dumpon, $dumpoff and their $end hold ordinary value changes
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.New("Error reading file : " + err.Error())
+	}
+	endStep()
+	return events, nil
+}
+
+// Function to read the words of a declaration up to its $end.
+func skipToEnd(scanner *bufio.Scanner) []string {
+	var fields []string
+	for scanner.Scan() && scanner.Text() != "$end" {
+		fields = append(fields, scanner.Text())
+	}
+	return fields
+}
+
+// Function to find the identifier of a variable by its full name, or by the last part of its name if that is unique.
+func findVariable(variables []vcdVariable, name string) (string, error) {
+	var matches []vcdVariable
+	for _, variable := range variables {
+		if variable.name == name {
+			return variable.id, nil
+		}
+		if strings.HasSuffix(variable.name, "."+name) {
+			matches = append(matches, variable)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", errors.New("Missing signal " + name)
+	case 1:
+		return matches[0].id, nil
+	}
+	// a signal seen from several modules is the same wire if they all share the identifier
+	for _, match := range matches[1:] {
+		if match.id != matches[0].id {
+			return "", errors.New("Signal " + name + " is ambiguous, found " + matches[0].name + " and " + match.name + ". Use the full name")
+		}
+	}
+	return matches[0].id, nil
+}
+
+// Function to check if a one bit signal is 1. Missing and unknown signals are not set.
+func isSet(values map[string]vcdValue, id string) bool {
+	value, isKnown := values[id]
+	return id != "" && isKnown && !value.isUnknown && value.value == 1
+}
+
+// Function to read the bits of a value, keeping the low 64 bits.
+func parseBits(bits string) vcdValue {
+	var value vcdValue
+	for _, bit := range strings.ToLower(bits) {
+		value.value <<= 1
+		switch bit {
+		case '1':
+			value.value |= 1
+		case '0':
+		default:
+			value.isUnknown = true
+		}
+	}
+	return value
+}
@@ -0,0 +1,300 @@
+package encoder
+
+import (
+	"errors"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	"strconv"
+	"strings"
+)
+
+// Instruction formats of LEGv8
+const (
+	R_FORMAT  = "R"
+	I_FORMAT  = "I"
+	D_FORMAT  = "D"
+	B_FORMAT  = "B"
+	CB_FORMAT = "CB"
+	IM_FORMAT = "IM"
+)
+
+// NOP is the encoding of ORR XZR, XZR, XZR, which changes nothing.
+// LEGv8 has no NOP, so it stands in for the empty instructions left by lines with only a label.
+const NOP uint32 = 0xAA1F03FF
+
+// Struct to describe how a mnemonic is encoded.
+// The opcode is the value of the top 11 bits of R and D, 10 bits of I, 9 bits of IM, 8 bits of CB and 6 bits of B instructions.
+type encoding struct {
+	format string
+	opcode uint32
+}
+
+// Encodings of the instructions ARMed runs, from the LEGv8 reference data card
+var encodings = map[string]encoding{
+	"ADD":   {R_FORMAT, 0x458},
+	"SUB":   {R_FORMAT, 0x658},
+	"ADDS":  {R_FORMAT, 0x558},
+	"SUBS":  {R_FORMAT, 0x758},
+	"AND":   {R_FORMAT, 0x450},
+	"ANDS":  {R_FORMAT, 0x750},
+	"ORR":   {R_FORMAT, 0x550},
+	"EOR":   {R_FORMAT, 0x650},
+	"MUL":   {R_FORMAT, 0x4D8},
+	"LSL":   {R_FORMAT, 0x69B},
+	"LSR":   {R_FORMAT, 0x69A},
+	"BR":    {R_FORMAT, 0x6B0},
+	"ADDI":  {I_FORMAT, 0x244},
+	"SUBI":  {I_FORMAT, 0x344},
+	"ADDIS": {I_FORMAT, 0x2C4},
+	"SUBIS": {I_FORMAT, 0x3C4},
+	"ANDI":  {I_FORMAT, 0x248},
+	"ANDIS": {I_FORMAT, 0x3C8},
+	"ORRI":  {I_FORMAT, 0x2C8},
+	"EORI":  {I_FORMAT, 0x348},
+	"LDUR":  {D_FORMAT, 0x7C2},
+	"STUR":  {D_FORMAT, 0x7C0},
+	"LDURH": {D_FORMAT, 0x3C2},
+	"STURH": {D_FORMAT, 0x3C0},
+	"LDURB": {D_FORMAT, 0x1C2},
+	"STURB": {D_FORMAT, 0x1C0},
+	"MOVZ":  {IM_FORMAT, 0x1A5},
+	"MOVK":  {IM_FORMAT, 0x1E5},
+	"B":     {B_FORMAT, 0x05},
+	"BL":    {B_FORMAT, 0x25},
+	"CBZ":   {CB_FORMAT, 0xB4},
+	"CBNZ":  {CB_FORMAT, 0xB5},
+	"B.":    {CB_FORMAT, 0x54},
+}
+
+// Condition codes held in the Rt field of B.cond
+var conditions = map[string]uint32{
+	"EQ": 0x0,
+	"NE": 0x1,
+	"HS": 0x2,
+	"LO": 0x3,
+	"HI": 0x8,
+	"LS": 0x9,
+	"GE": 0xA,
+	"LT": 0xB,
+	"GT": 0xC,
+	"LE": 0xD,
+}
+
+// Encoder turns the instructions of a linked program into LEGv8 machine code.
+type Encoder struct {
+	labels map[string]int64
+}
+
+// New is a function to create an encoder for a linked program, which resolves branches to its text labels.
+func New(program *Assembler.Object) *Encoder {
+	encoder := Encoder{labels: make(map[string]int64)}
+	for _, symbol := range program.Symbols {
+		if symbol.Section == Assembler.TEXT_SECTION {
+			encoder.labels[symbol.Name] = symbol.Value
+		}
+	}
+	return &encoder
+}
+
+// EncodeProgram is a function to encode every instruction of a linked program, one word per instruction.
+// Errors are reported with the location of the instruction, and all of them are returned together.
+func EncodeProgram(program *Assembler.Object) ([]uint32, error) {
+	encoder := New(program)
+	words := make([]uint32, len(program.Text))
+	var errorList Assembler.ErrorList
+	for PC, statement := range program.Text {
+		word, err := encoder.Encode(statement.Text, int64(PC))
+		if err != nil {
+			errorList = errorList.Append(errors.New(statement.Location.String() + ": " + err.Error()))
+		}
+		words[PC] = word
+	}
+	if len(errorList) != 0 {
+		return nil, errorList
+	}
+	return words, nil
+}
+
+// Encode is a method to encode a single instruction found at instruction number PC.
+// Branch offsets are counted in instructions, so an instruction memory indexed by PC/4 runs the code unchanged.
+func (encoder *Encoder) Encode(text string, PC int64) (uint32, error) {
+	if text == "" {
+		return NOP, nil
+	}
+	instruction, err := Assembler.ParseInstruction(text)
+	if err != nil {
+		return 0, err
+	}
+	mnemonic := instruction.Mnemonic
+	if strings.HasPrefix(mnemonic, "B.") {
+		mnemonic = "B."
+	}
+	format, isKnown := encodings[mnemonic]
+	if !isKnown {
+		return 0, errors.New("Can not encode unknown instruction " + text)
+	}
+
+	operands := instruction.Operands
+	switch format.format {
+
+	case R_FORMAT:
+		return encoder.encodeR(instruction, format.opcode)
+
+	case I_FORMAT:
+		err = instruction.CheckOperands(Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+		if err != nil {
+			return 0, err
+		}
+		immediate, err := evaluate(operands[2].Expression, -4095, 4095, text)
+		if err != nil {
+			return 0, err
+		}
+		opcode := format.opcode
+		if immediate < 0 {
+			// ADDI and SUBI with a negative constant are the other instruction with its magnitude
+			switch mnemonic {
+			case "ADDI":
+				opcode, immediate = encodings["SUBI"].opcode, -immediate
+			case "SUBI":
+				opcode, immediate = encodings["ADDI"].opcode, -immediate
+			default:
+				return 0, errors.New("Immediate " + strconv.FormatInt(immediate, 10) + " does not fit in the 12-bit unsigned immediate of " + text)
+			}
+		}
+		return opcode<<22 | uint32(immediate)<<10 | uint32(operands[1].Register)<<5 | uint32(operands[0].Register), nil
+
+	case D_FORMAT:
+		err = instruction.CheckOperands(Assembler.REGISTER_OPERAND, Assembler.ADDRESS_OPERAND)
+		if err != nil {
+			return 0, err
+		}
+		offset, err := evaluate(operands[1].Expression, -256, 255, text)
+		if err != nil {
+			return 0, err
+		}
+		return format.opcode<<21 | field(offset, 9)<<12 | uint32(operands[1].Register)<<5 | uint32(operands[0].Register), nil
+
+	case IM_FORMAT:
+		err = instruction.CheckOperands(Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND, Assembler.SHIFT_OPERAND)
+		if err != nil {
+			return 0, err
+		}
+		immediate, err := evaluate(operands[1].Expression, 0, 65535, text)
+		if err != nil {
+			return 0, err
+		}
+		shift, err := evaluate(operands[2].Expression, 0, 48, text)
+		if err != nil {
+			return 0, err
+		}
+		// the shift is written either in bits or as a multiple of 16 bits
+		if shift > 3 {
+			if shift%16 != 0 {
+				return 0, errors.New("Invalid shift " + strconv.FormatInt(shift, 10) + ", expected 0, 16, 32 or 48 in " + text)
+			}
+			shift /= 16
+		}
+		return format.opcode<<23 | uint32(shift)<<21 | uint32(immediate)<<5 | uint32(operands[0].Register), nil
+
+	case B_FORMAT:
+		err = instruction.CheckOperands(Assembler.LABEL_OPERAND)
+		if err != nil {
+			return 0, err
+		}
+		offset, err := encoder.branchOffset(operands[0].Expression, PC, 26, text)
+		if err != nil {
+			return 0, err
+		}
+		return format.opcode<<26 | offset, nil
+	}
+
+	// CB format
+	target := operands
+	var register uint32
+	if mnemonic == "B." {
+		condition, isCondition := conditions[strings.TrimPrefix(instruction.Mnemonic, "B.")]
+		if !isCondition {
+			return 0, errors.New("Can not encode unknown condition of " + text)
+		}
+		err = instruction.CheckOperands(Assembler.LABEL_OPERAND)
+		register = condition
+	} else {
+		err = instruction.CheckOperands(Assembler.REGISTER_OPERAND, Assembler.LABEL_OPERAND)
+		if err == nil {
+			register, target = uint32(operands[0].Register), operands[1:]
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	offset, err := encoder.branchOffset(target[0].Expression, PC, 19, text)
+	if err != nil {
+		return 0, err
+	}
+	return format.opcode<<24 | offset<<5 | register, nil
+}
+
+// Method to encode an instruction of the R format, opcode | Rm | shamt | Rn | Rd.
+// Shifts hold their amount in shamt, MUL has shamt 0x1F and BR only uses Rn.
+func (encoder *Encoder) encodeR(instruction *Assembler.Instruction, opcode uint32) (uint32, error) {
+	operands := instruction.Operands
+	switch instruction.Mnemonic {
+	case "BR":
+		err := instruction.CheckOperands(Assembler.REGISTER_OPERAND)
+		if err != nil {
+			return 0, err
+		}
+		return opcode<<21 | uint32(operands[0].Register)<<5, nil
+
+	case "LSL", "LSR":
+		err := instruction.CheckOperands(Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+		if err != nil {
+			return 0, err
+		}
+		amount, err := evaluate(operands[2].Expression, 0, 63, instruction.String())
+		if err != nil {
+			return 0, err
+		}
+		return opcode<<21 | uint32(amount)<<10 | uint32(operands[1].Register)<<5 | uint32(operands[0].Register), nil
+	}
+
+	err := instruction.CheckOperands(Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
+	if err != nil {
+		return 0, err
+	}
+	var shamt uint32
+	if instruction.Mnemonic == "MUL" {
+		shamt = 0x1F
+	}
+	return opcode<<21 | uint32(operands[2].Register)<<16 | shamt<<10 | uint32(operands[1].Register)<<5 | uint32(operands[0].Register), nil
+}
+
+// Method to find the offset from an instruction to a label, in instructions, as a field of the given number of bits.
+func (encoder *Encoder) branchOffset(label string, PC int64, bits uint, text string) (uint32, error) {
+	labelPC, isValidLabel := encoder.labels[label]
+	if !isValidLabel {
+		return 0, errors.New("Invalid label name " + label + " in " + text)
+	}
+	offset := labelPC - PC
+	limit := int64(1) << (bits - 1)
+	if offset < -limit || offset >= limit {
+		return 0, errors.New("Branch offset " + strconv.FormatInt(offset, 10) + " does not fit in the " + strconv.Itoa(int(bits)) + "-bit offset of " + text)
+	}
+	return field(offset, bits), nil
+}
+
+// Function to evaluate a constant expression, with or without '#', and check that it is in a range.
+func evaluate(expression string, minimum, maximum int64, text string) (int64, error) {
+	value, err := Assembler.Evaluate(strings.TrimPrefix(strings.TrimSpace(expression), "#"), nil)
+	if err != nil {
+		return 0, errors.New(err.Error() + " in " + text)
+	}
+	if value < minimum || value > maximum {
+		return 0, errors.New("Value " + strconv.FormatInt(value, 10) + " is outside " + strconv.FormatInt(minimum, 10) + " to " + strconv.FormatInt(maximum, 10) + " in " + text)
+	}
+	return value, nil
+}
+
+// Function to keep the low bits of a two's complement value, to fill a field of an instruction.
+func field(value int64, bits uint) uint32 {
+	return uint32(value) & (uint32(1)<<bits - 1)
+}
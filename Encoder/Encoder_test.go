@@ -0,0 +1,81 @@
+package encoder
+
+import (
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	"strings"
+	"testing"
+)
+
+// Function to create an encoder for a program with a text label at each given instruction number.
+func newEncoder(labels map[string]int64) *Encoder {
+	var program Assembler.Object
+	for name, PC := range labels {
+		program.Symbols = append(program.Symbols, Assembler.Symbol{Name: name, Section: Assembler.TEXT_SECTION, Value: PC})
+	}
+	return New(&program)
+}
+
+// The expected words follow the field layouts and opcodes of the LEGv8 reference data card.
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		text string
+		PC   int64
+		want uint32
+	}{
+		// R: opcode 0x458 | Rm 3 | shamt 0 | Rn 2 | Rd 1
+		{"ADD X1, X2, X3", 0, 0x8B030041},
+		{"SUB X9, X10, X11", 0, 0xCB0B0149},
+		{"MUL X1, X2, X3", 0, 0x9B037C41},
+		{"LSL X1, X2, #4", 0, 0xD3601041},
+		{"BR LR", 0, 0xD60003C0},
+		// I: opcode 0x244 | ALU_immediate 3 | Rn 31 | Rd 0
+		{"ADDI X0, XZR, #3", 0, 0x91000FE0},
+		{"SUBI X1, X2, #16", 0, 0xD1004041},
+		{"ADDI X1, X2, #-16", 0, 0xD1004041},
+		// D: opcode 0x7C2 | DT_address 8 | op 0 | Rn 2 | Rt 1
+		{"LDUR X1, [X2, #8]", 0, 0xF8408041},
+		{"STUR X1, [SP, #-8]", 0, 0xF81F8381},
+		// IM: opcode 0x1A5 | LSL 1 | MOV_immediate 0x1234 | Rd 1
+		{"MOVZ X1, #0x1234, LSL #16", 0, 0xD2A24681},
+		{"MOVZ X1, #0x1234, LSL 1", 0, 0xD2A24681},
+		// B: opcode 0x05 | BR_address -2
+		{"B loop", 2, 0x17FFFFFE},
+		{"BL function", 1, 0x94000003},
+		// CB: opcode 0xB4 | COND_BR_address 2 | Rt 1
+		{"CBZ X1, done", 3, 0xB4000041},
+		{"B.NE loop", 1, 0x54FFFFE1},
+		{"", 0, NOP},
+	}
+	encoder := newEncoder(map[string]int64{"loop": 0, "function": 4, "done": 5})
+	for _, test := range tests {
+		word, err := encoder.Encode(test.text, test.PC)
+		if err != nil {
+			t.Errorf("Encode(%q) failed: %v", test.text, err)
+		} else if word != test.want {
+			t.Errorf("Encode(%q) = 0x%08X, want 0x%08X", test.text, word, test.want)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"ADDI X1, X2, #4096", "Value 4096 is outside"},
+		{"ANDI X1, X2, #-1", "Immediate -1 does not fit"},
+		{"LDUR X1, [X2, #256]", "Value 256 is outside"},
+		{"MOVZ X1, #1, LSL #8", "Invalid shift 8"},
+		{"B nowhere", "Invalid label name nowhere"},
+		{"B.AL loop", "Can not encode unknown condition"},
+	}
+	encoder := newEncoder(map[string]int64{"loop": 0})
+	for _, test := range tests {
+		_, err := encoder.Encode(test.text, 0)
+		if err == nil {
+			t.Errorf("Encode(%q) succeeded, want an error", test.text)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Encode(%q) failed with %q, want %q", test.text, err.Error(), test.want)
+		}
+	}
+}
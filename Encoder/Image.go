@@ -0,0 +1,150 @@
+package encoder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Formats of memory images
+const (
+	READMEMH_FORMAT = "readmemh"
+	READMEMB_FORMAT = "readmemb"
+	LOGISIM_FORMAT  = "logisim"
+	MIF_FORMAT      = "mif"
+)
+
+// Number of words on each line of a Logisim image
+const LOGISIM_WORDS_PER_LINE = 8
+
+// Shortest run of equal words that a Logisim image or MIF file writes once with a count
+const MIN_RUN = 4
+
+// Extension is a function to find the usual extension of the files of a format.
+func Extension(format string) (string, error) {
+	switch format {
+	case READMEMH_FORMAT, READMEMB_FORMAT:
+		return ".mem", nil
+	case LOGISIM_FORMAT:
+		return ".img", nil
+	case MIF_FORMAT:
+		return ".mif", nil
+	}
+	return "", errors.New("Unknown image format " + format + ", expected readmemh, readmemb, logisim or mif")
+}
+
+// WriteImage is a function to write 32-bit words to a memory image, one word per address.
+// Comments, if given, are written next to the word of the same index where the format allows it.
+//
+//	readmemh   hex words for $readmemh in Verilog, one per line
+//	readmemb   binary words for $readmemb
+//	logisim    a "v2.0 raw" image for Logisim and Digital
+//	mif        a Memory Initialization File for Quartus
+func WriteImage(fileName string, format string, words []uint32, comments []string) error {
+	if _, err := Extension(format); err != nil {
+		return err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return errors.New("Error creating file : " + err.Error())
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	switch format {
+	case READMEMH_FORMAT:
+		writeReadmem(writer, words, comments, "%08X")
+	case READMEMB_FORMAT:
+		writeReadmem(writer, words, comments, "%032b")
+	case LOGISIM_FORMAT:
+		writeLogisim(writer, words)
+	case MIF_FORMAT:
+		writeMIF(writer, words, comments)
+	}
+	return writer.Flush()
+}
+
+// Function to find the comment of a word, if there is one.
+func commentOf(comments []string, index int) string {
+	if index < len(comments) {
+		return comments[index]
+	}
+	return ""
+}
+
+// Function to find how many words starting at an index are equal to it.
+func runLength(words []uint32, index int) int {
+	length := 1
+	for index+length < len(words) && words[index+length] == words[index] {
+		length++
+	}
+	return length
+}
+
+func writeReadmem(writer *bufio.Writer, words []uint32, comments []string, digits string) {
+	for i, word := range words {
+		fmt.Fprintf(writer, digits, word)
+		if comment := commentOf(comments, i); comment != "" {
+			fmt.Fprintf(writer, " // %03X: %s", i*4, comment)
+		}
+		fmt.Fprintf(writer, "\n")
+	}
+}
+
+// Function to write a Logisim image, where a run of equal words is written as COUNT*WORD.
+func writeLogisim(writer *bufio.Writer, words []uint32) {
+	fmt.Fprintf(writer, "v2.0 raw\n")
+	column := 0
+	for i := 0; i < len(words); {
+		length := runLength(words, i)
+		if length >= MIN_RUN {
+			fmt.Fprintf(writer, "%d*%x", length, words[i])
+		} else {
+			length = 1
+			fmt.Fprintf(writer, "%x", words[i])
+		}
+		i += length
+
+		column++
+		if column == LOGISIM_WORDS_PER_LINE || i == len(words) {
+			fmt.Fprintf(writer, "\n")
+			column = 0
+		} else {
+			fmt.Fprintf(writer, " ")
+		}
+	}
+}
+
+// Function to write a MIF file, where a run of equal words is written once for a range of addresses.
+// Addresses count words, since each address holds a word.
+func writeMIF(writer *bufio.Writer, words []uint32, comments []string) {
+	depth := len(words)
+	if depth == 0 {
+		// MIF files need at least one word
+		depth, words = 1, []uint32{NOP}
+	}
+	addressDigits := len(strconv.FormatInt(int64(depth-1), 16))
+
+	fmt.Fprintf(writer, "WIDTH=32;\n")
+	fmt.Fprintf(writer, "DEPTH=%d;\n\n", depth)
+	fmt.Fprintf(writer, "ADDRESS_RADIX=HEX;\n")
+	fmt.Fprintf(writer, "DATA_RADIX=HEX;\n\n")
+	fmt.Fprintf(writer, "CONTENT BEGIN\n")
+	for i := 0; i < len(words); {
+		length := runLength(words, i)
+		if length >= MIN_RUN && comments == nil {
+			fmt.Fprintf(writer, "\t[%0*X..%0*X] : %08X;\n", addressDigits, i, addressDigits, i+length-1, words[i])
+			i += length
+			continue
+		}
+		fmt.Fprintf(writer, "\t%0*X : %08X;", addressDigits, i, words[i])
+		if comment := commentOf(comments, i); comment != "" {
+			fmt.Fprintf(writer, " -- %s", comment)
+		}
+		fmt.Fprintf(writer, "\n")
+		i++
+	}
+	fmt.Fprintf(writer, "END;\n")
+}
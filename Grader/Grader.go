@@ -0,0 +1,144 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Limits "github.com/coderick14/ARMed/Limits"
+	Memory "github.com/coderick14/ARMed/Memory"
+	color "github.com/fatih/color"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"strconv"
+)
+
+// Exit code of a program that ran past its last instruction, and of one that failed
+const (
+	SUCCESS_STATUS = 0
+	ERROR_STATUS   = 1
+)
+
+// Mismatch is a value that differs from what the case expected.
+type Mismatch struct {
+	What     string
+	Expected string
+	Actual   string
+}
+
+// Result is the outcome of a case. Error is set if the case could not be run at all.
+type Result struct {
+	Name       string
+	Error      error
+	ExitCode   int
+	Reason     string
+	Mismatches []Mismatch
+}
+
+// IsPassed is a method to check if a case ran and matched everything it expected.
+func (result *Result) IsPassed() bool {
+	return result.Error == nil && len(result.Mismatches) == 0
+}
+
+// Failed is a function to record a case whose program could not be built.
+func Failed(testCase *Case, err error) *Result {
+	return &Result{Name: testCase.Name, Error: err}
+}
+
+// Run is a function to run a case on a linked program, and compare the final state with the expected one.
+func Run(testCase *Case, program *Assembler.Object) *Result {
+	result := Result{Name: testCase.Name}
+	Memory.ResetMachine()
+	err := Memory.LoadProgram(program)
+	if err != nil {
+		result.Error = err
+		return &result
+	}
+	Memory.InitRegisters()
+	for register, value := range testCase.Registers {
+		Memory.SetRegisterValue(register, value)
+	}
+	for _, words := range testCase.Memory {
+		for i, word := range words.Words {
+			Memory.SetMemoryWord(words.Address/Memory.WORD_SIZE+int64(i), int32(word.Low))
+		}
+	}
+
+	var instructions int64
+	for Memory.IsValidPC(Memory.InstructionMem.PC) {
+		if instructions == testCase.MaxInstructions {
+			result.ExitCode = Limits.INSTRUCTION_LIMIT_STATUS
+			result.Reason = "Instruction limit of " + strconv.FormatInt(testCase.MaxInstructions, 10) + " reached"
+			break
+		}
+		location := Memory.InstructionMem.Locations[Memory.InstructionMem.PC]
+		err = Memory.InstructionMem.ValidateAndExecuteInstruction()
+		instructions++
+		if err != nil {
+			result.ExitCode = ERROR_STATUS
+			result.Reason = location.String() + ": " + err.Error()
+			break
+		}
+	}
+
+	expected := testCase.Expected
+	if expected.ExitCode != result.ExitCode {
+		actual := strconv.Itoa(result.ExitCode)
+		if result.Reason != "" {
+			actual += " (" + result.Reason + ")"
+		}
+		result.mismatch("exit code", strconv.Itoa(expected.ExitCode), actual)
+	}
+	for _, register := range expected.Registers {
+		value := Memory.GetRegisterValue(register)
+		if !expected.Values[register].Matches(value) {
+			result.mismatch(Memory.RegisterName(int(register)), expected.Values[register].String(), strconv.FormatInt(value, 10))
+		}
+	}
+	for _, words := range expected.Memory {
+		for i, word := range words.Words {
+			address := words.Address + int64(i)*Memory.WORD_SIZE
+			value := int64(Memory.GetMemoryWord(address / Memory.WORD_SIZE))
+			if !word.Matches(value) {
+				result.mismatch("["+strconv.FormatInt(address, 10)+"]", word.String(), strconv.FormatInt(value, 10))
+			}
+		}
+	}
+	return &result
+}
+
+// Method to record a value that differs from the expected one.
+func (result *Result) mismatch(what string, expected string, actual string) {
+	result.Mismatches = append(result.Mismatches, Mismatch{what, expected, actual})
+}
+
+// ShowResults is a function to print whether each case passed, with a table of the mismatches of each failed case.
+// Returns an error if any case failed.
+func ShowResults(results []*Result) error {
+	passed := 0
+	for _, result := range results {
+		if result.IsPassed() {
+			passed++
+			fmt.Println(color.GreenString("PASS"), result.Name)
+			continue
+		}
+		fmt.Println(color.RedString("FAIL"), result.Name)
+		if result.Error != nil {
+			fmt.Println(result.Error)
+			fmt.Printf("\n")
+			continue
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Check", "Expected", "Actual"})
+		for _, mismatch := range result.Mismatches {
+			table.Append([]string{mismatch.What, mismatch.Expected, mismatch.Actual})
+		}
+		table.Render()
+		fmt.Printf("\n")
+	}
+
+	fmt.Println("Passed", passed, "of", len(results), "cases")
+	if passed != len(results) {
+		return errors.New(strconv.Itoa(len(results)-passed) + " cases failed")
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package grader
+
+import (
+	"errors"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DEFAULT_MAX_INSTRUCTIONS is the number of instructions after which a case is stopped, unless the spec says otherwise
+const DEFAULT_MAX_INSTRUCTIONS = 1000000
+
+// DONT_CARE matches any value
+const DONT_CARE = "_"
+
+// Value is an expected value: any value, a single value or a range such as 10..20.
+type Value struct {
+	IsAny bool
+	Low   int64
+	High  int64
+}
+
+// Matches is a method to check if a value is expected.
+func (value Value) Matches(actual int64) bool {
+	return value.IsAny || (actual >= value.Low && actual <= value.High)
+}
+
+// String is a method to write a value the way it is written in a spec.
+func (value Value) String() string {
+	switch {
+	case value.IsAny:
+		return DONT_CARE
+	case value.Low == value.High:
+		return strconv.FormatInt(value.Low, 10)
+	}
+	return strconv.FormatInt(value.Low, 10) + ".." + strconv.FormatInt(value.High, 10)
+}
+
+// MemoryRange is a list of consecutive words, starting at a byte address.
+type MemoryRange struct {
+	Address int64
+	Words   []Value
+}
+
+// Case is a single test of a spec. The registers and memory are set after the program is loaded.
+// Registers and memory that are not expected are not checked. The exit code is always checked, and is 0 unless expected otherwise.
+type Case struct {
+	Name            string
+	Programs        []string
+	MaxInstructions int64
+	Registers       map[uint]int64
+	Memory          []MemoryRange
+	Expected        Expectation
+}
+
+// Expectation is what the state of the machine should be once a case ends.
+type Expectation struct {
+	Registers []uint
+	Values    map[uint]Value
+	Memory    []MemoryRange
+	ExitCode  int
+}
+
+// Struct to hold the state of a spec being read
+type specReader struct {
+	directory string
+}
+
+// ReadSpec is a function to read the cases of a test specification. Programs are found relative to the spec.
+//
+//	program: fact.s
+//	cases:
+//	  - name: factorial of 3
+//	    registers:
+//	      X0: 3
+//	    expect:
+//	      registers:
+//	        X1: 6
+//	        X9: _              # any value
+//	      memory:
+//	        16376: [3, 10..20]
+//	      exit_code: 0
+func ReadSpec(fileName string) ([]*Case, error) {
+	text, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.New("Error reading file : " + err.Error())
+	}
+	root, err := ParseYAML(string(text))
+	if err == nil {
+		reader := specReader{directory: filepath.Dir(fileName)}
+		var cases []*Case
+		cases, err = reader.readSpec(root)
+		if err == nil {
+			return cases, nil
+		}
+	}
+	return nil, errors.New(fileName + ": " + err.Error())
+}
+
+// Function to check that a mapping only has known keys.
+func checkKeys(node *Node, what string, keys ...string) error {
+	if node.Kind != MAP_NODE {
+		return lineError(node.Line, "Expected a mapping for "+what)
+	}
+	for _, key := range node.Keys {
+		isKnown := false
+		for _, known := range keys {
+			isKnown = isKnown || key == known
+		}
+		if !isKnown {
+			return lineError(node.KeyLines[key], "Unknown key "+key+" in "+what+", expected one of "+strings.Join(keys, ", "))
+		}
+	}
+	return nil
+}
+
+// Method to read the whole spec.
+func (reader *specReader) readSpec(root *Node) ([]*Case, error) {
+	err := checkKeys(root, "the spec", "program", "max_instructions", "cases")
+	if err != nil {
+		return nil, err
+	}
+	defaults := Case{MaxInstructions: DEFAULT_MAX_INSTRUCTIONS}
+	err = reader.readSettings(root, &defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	list, isKnown := root.Values["cases"]
+	if !isKnown || list.Kind != LIST_NODE || len(list.Items) == 0 {
+		return nil, lineError(root.Line, "Expected a list of cases")
+	}
+	var cases []*Case
+	for i, node := range list.Items {
+		testCase := defaults
+		testCase.Name = "case " + strconv.Itoa(i+1)
+		err = reader.readCase(node, &testCase)
+		if err != nil {
+			return nil, err
+		}
+		if len(testCase.Programs) == 0 {
+			return nil, lineError(node.Line, "Missing program for "+testCase.Name)
+		}
+		cases = append(cases, &testCase)
+	}
+	return cases, nil
+}
+
+// Method to read the program and instruction limit, which may be given for the whole spec or for a case.
+func (reader *specReader) readSettings(node *Node, testCase *Case) error {
+	if program, isKnown := node.Values["program"]; isKnown {
+		testCase.Programs = nil
+		items := []*Node{program}
+		if program.Kind == LIST_NODE {
+			items = program.Items
+		}
+		for _, item := range items {
+			if item.Kind != SCALAR_NODE || item.Value == "" {
+				return lineError(item.Line, "Expected the name of a source or object file")
+			}
+			testCase.Programs = append(testCase.Programs, filepath.Join(reader.directory, item.Value))
+		}
+	}
+	if limit, isKnown := node.Values["max_instructions"]; isKnown {
+		value, err := readNumber(limit)
+		if err != nil {
+			return err
+		}
+		testCase.MaxInstructions = value
+	}
+	return nil
+}
+
+// Method to read a case.
+func (reader *specReader) readCase(node *Node, testCase *Case) error {
+	err := checkKeys(node, "a case", "name", "program", "max_instructions", "registers", "memory", "stdin", "expect")
+	if err != nil {
+		return err
+	}
+	if name, isKnown := node.Values["name"]; isKnown {
+		testCase.Name = name.Value
+	}
+	err = reader.readSettings(node, testCase)
+	if err != nil {
+		return err
+	}
+	// programs have no instructions to read input or write output, so only empty streams can be checked
+	if stdin, isKnown := node.Values["stdin"]; isKnown && stdin.Value != "" {
+		return lineError(stdin.Line, "ARMed programs can not read input, so stdin must be empty")
+	}
+
+	testCase.Registers = make(map[uint]int64)
+	if registers, isKnown := node.Values["registers"]; isKnown {
+		names, values, err := readRegisters(registers)
+		if err != nil {
+			return err
+		}
+		for _, register := range names {
+			if values[register].IsAny || values[register].Low != values[register].High {
+				return lineError(registers.Line, "Initial registers need a single value")
+			}
+			testCase.Registers[register] = values[register].Low
+		}
+	}
+	if memory, isKnown := node.Values["memory"]; isKnown {
+		testCase.Memory, err = readMemory(memory, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	expect, isKnown := node.Values["expect"]
+	if !isKnown {
+		return nil
+	}
+	err = checkKeys(expect, "expect", "registers", "memory", "stdout", "exit_code")
+	if err != nil {
+		return err
+	}
+	expected := &testCase.Expected
+	if registers, isKnown := expect.Values["registers"]; isKnown {
+		expected.Registers, expected.Values, err = readRegisters(registers)
+		if err != nil {
+			return err
+		}
+	}
+	if memory, isKnown := expect.Values["memory"]; isKnown {
+		expected.Memory, err = readMemory(memory, true)
+		if err != nil {
+			return err
+		}
+	}
+	if stdout, isKnown := expect.Values["stdout"]; isKnown && stdout.Value != "" {
+		return lineError(stdout.Line, "ARMed programs can not write output, so stdout must be empty")
+	}
+	if code, isKnown := expect.Values["exit_code"]; isKnown {
+		value, err := readNumber(code)
+		if err != nil {
+			return err
+		}
+		expected.ExitCode = int(value)
+	}
+	return nil
+}
+
+// Function to read a number, written as a constant expression.
+func readNumber(node *Node) (int64, error) {
+	if node.Kind != SCALAR_NODE {
+		return 0, lineError(node.Line, "Expected a number")
+	}
+	value, err := Assembler.Evaluate(node.Value, nil)
+	if err != nil {
+		return 0, lineError(node.Line, "Invalid number "+node.Value+" : "+err.Error())
+	}
+	return value, nil
+}
+
+// Function to read an expected value: _, a number or a range of numbers.
+func readValue(node *Node) (Value, error) {
+	if node.Kind == SCALAR_NODE && node.Value == DONT_CARE {
+		return Value{IsAny: true}, nil
+	}
+	if node.Kind == SCALAR_NODE && strings.Contains(node.Value, "..") {
+		bounds := strings.SplitN(node.Value, "..", 2)
+		low, err := readNumber(&Node{Kind: SCALAR_NODE, Line: node.Line, Value: bounds[0]})
+		if err != nil {
+			return Value{}, err
+		}
+		high, err := readNumber(&Node{Kind: SCALAR_NODE, Line: node.Line, Value: bounds[1]})
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Low: low, High: high}, nil
+	}
+	value, err := readNumber(node)
+	return Value{Low: value, High: value}, err
+}
+
+// Function to read a mapping of register names to values, keeping the order they were written in.
+func readRegisters(node *Node) ([]uint, map[uint]Value, error) {
+	if node.Kind != MAP_NODE {
+		return nil, nil, lineError(node.Line, "Expected registers such as X0: 5")
+	}
+	var registers []uint
+	values := make(map[uint]Value)
+	for _, name := range node.Keys {
+		register, isRegister := Assembler.RegisterNumber(name)
+		if !isRegister {
+			return nil, nil, lineError(node.KeyLines[name], "Unknown register "+name)
+		}
+		value, err := readValue(node.Values[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, isKnown := values[register]; !isKnown {
+			registers = append(registers, register)
+		}
+		values[register] = value
+	}
+	return registers, values, nil
+}
+
+// Function to read a mapping of byte addresses to a word or a list of words.
+// Only expected memory may use _ and ranges.
+func readMemory(node *Node, isExpected bool) ([]MemoryRange, error) {
+	if node.Kind != MAP_NODE {
+		return nil, lineError(node.Line, "Expected memory such as 256: [1, 2, 3]")
+	}
+	var ranges []MemoryRange
+	for _, key := range node.Keys {
+		address, err := readNumber(&Node{Kind: SCALAR_NODE, Line: node.KeyLines[key], Value: key})
+		if err != nil {
+			return nil, err
+		}
+		words := []*Node{node.Values[key]}
+		if words[0].Kind == LIST_NODE {
+			words = words[0].Items
+		}
+		if address < 0 || address%Memory.WORD_SIZE != 0 || address/Memory.WORD_SIZE+int64(len(words)) > Memory.MEMORY_SIZE {
+			return nil, lineError(node.KeyLines[key], "Memory at "+key+" must be word aligned and inside data memory")
+		}
+
+		current := MemoryRange{Address: address}
+		for _, word := range words {
+			value, err := readValue(word)
+			if err != nil {
+				return nil, err
+			}
+			if !isExpected && (value.IsAny || value.Low != value.High) {
+				return nil, lineError(word.Line, "Initial memory needs a single value")
+			}
+			current.Words = append(current.Words, value)
+		}
+		ranges = append(ranges, current)
+	}
+	return ranges, nil
+}
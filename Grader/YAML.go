@@ -0,0 +1,246 @@
+package grader
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Kinds of YAML nodes
+const (
+	SCALAR_NODE = iota
+	MAP_NODE
+	LIST_NODE
+)
+
+// Node is a value read from a YAML file: a scalar, a mapping or a sequence.
+// The keys of a mapping are kept in the order they were written, together with their lines.
+type Node struct {
+	Kind     int
+	Line     int
+	Value    string
+	Keys     []string
+	KeyLines map[string]int
+	Values   map[string]*Node
+	Items    []*Node
+}
+
+// Struct to represent a line of YAML without its indentation and comment
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// Struct to hold the state of a YAML file being parsed
+type yamlParser struct {
+	lines []yamlLine
+	index int
+}
+
+// ParseYAML is a function to read the subset of YAML used by test specifications:
+// block mappings and sequences, flow sequences such as [1, 2, _], plain and quoted scalars, and comments.
+func ParseYAML(text string) (*Node, error) {
+	parser := yamlParser{}
+	for i, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		content := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(content, "\t") {
+			return nil, lineError(i+1, "Tabs can not be used for indentation")
+		}
+		content = strings.TrimRight(stripComment(content), " \t")
+		if content == "" || content == "---" {
+			continue
+		}
+		parser.lines = append(parser.lines, yamlLine{i + 1, len(line) - len(strings.TrimLeft(line, " ")), content})
+	}
+	if len(parser.lines) == 0 {
+		return &Node{Kind: MAP_NODE, KeyLines: make(map[string]int), Values: make(map[string]*Node)}, nil
+	}
+
+	node, err := parser.parseBlock(parser.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if parser.index < len(parser.lines) {
+		return nil, lineError(parser.lines[parser.index].number, "Unexpected indentation")
+	}
+	return node, nil
+}
+
+// Function to make an error for a line of the file.
+func lineError(line int, message string) error {
+	return errors.New("line " + strconv.Itoa(line) + ": " + message)
+}
+
+// Function to remove a comment, which starts with a # at the start of the line or after a space, outside quotes.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote != 0:
+			if text[i] == quote {
+				quote = 0
+			}
+		case text[i] == '"' || text[i] == '\'':
+			quote = text[i]
+		case text[i] == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// Function to check if a line is an item of a sequence.
+func isItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// Method to parse the mapping or sequence whose lines start at the given indentation.
+func (parser *yamlParser) parseBlock(indent int) (*Node, error) {
+	if isItem(parser.lines[parser.index].text) {
+		return parser.parseSequence(indent)
+	}
+	return parser.parseMapping(indent)
+}
+
+// Method to parse the value of a key or an item that continues on the next lines.
+// A sequence may start at the same indentation as the key it belongs to.
+func (parser *yamlParser) parseNested(indent int, line int) (*Node, error) {
+	if parser.index < len(parser.lines) {
+		next := parser.lines[parser.index]
+		if next.indent > indent || (next.indent == indent && isItem(next.text)) {
+			return parser.parseBlock(next.indent)
+		}
+	}
+	return &Node{Kind: SCALAR_NODE, Line: line}, nil
+}
+
+// Method to parse a sequence of "- item" lines.
+func (parser *yamlParser) parseSequence(indent int) (*Node, error) {
+	node := Node{Kind: LIST_NODE, Line: parser.lines[parser.index].number}
+	for parser.index < len(parser.lines) {
+		line := parser.lines[parser.index]
+		if line.indent != indent || !isItem(line.text) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		var item *Node
+		var err error
+		switch {
+		case rest == "":
+			parser.index++
+			item, err = parser.parseNested(indent, line.number)
+		case strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "\"") || strings.HasPrefix(rest, "'") || splitKey(rest) < 0:
+			parser.index++
+			item, err = parseValue(rest, line.number)
+		default:
+			// a mapping that starts on the line of the item continues at the indentation of its first key
+			itemIndent := indent + len(line.text) - len(rest)
+			parser.lines[parser.index] = yamlLine{line.number, itemIndent, rest}
+			item, err = parser.parseMapping(itemIndent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Items = append(node.Items, item)
+	}
+	return &node, nil
+}
+
+// Function to find the colon that ends the key of a "key: value" line, or -1 if there is none.
+func splitKey(text string) int {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote != 0:
+			if text[i] == quote {
+				quote = 0
+			}
+		case text[i] == '"' || text[i] == '\'':
+			quote = text[i]
+		case text[i] == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return i
+		}
+	}
+	return -1
+}
+
+// Method to parse a mapping of "key: value" lines.
+func (parser *yamlParser) parseMapping(indent int) (*Node, error) {
+	node := Node{Kind: MAP_NODE, Line: parser.lines[parser.index].number, KeyLines: make(map[string]int), Values: make(map[string]*Node)}
+	for parser.index < len(parser.lines) {
+		line := parser.lines[parser.index]
+		if line.indent != indent || isItem(line.text) {
+			break
+		}
+		colon := splitKey(line.text)
+		if colon < 0 {
+			return nil, lineError(line.number, "Expected key: value, found "+line.text)
+		}
+		key, err := parseScalar(strings.TrimSpace(line.text[:colon]), line.number)
+		if err != nil {
+			return nil, err
+		}
+		if _, isDuplicate := node.Values[key]; isDuplicate {
+			return nil, lineError(line.number, "Duplicate key "+key)
+		}
+
+		rest := strings.TrimSpace(line.text[colon+1:])
+		parser.index++
+		var value *Node
+		if rest == "" {
+			value, err = parser.parseNested(indent, line.number)
+		} else {
+			value, err = parseValue(rest, line.number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Keys = append(node.Keys, key)
+		node.KeyLines[key] = line.number
+		node.Values[key] = value
+	}
+	return &node, nil
+}
+
+// Function to parse a value written on a single line, a flow sequence or a scalar.
+func parseValue(text string, line int) (*Node, error) {
+	if !strings.HasPrefix(text, "[") {
+		value, err := parseScalar(text, line)
+		return &Node{Kind: SCALAR_NODE, Line: line, Value: value}, err
+	}
+	if !strings.HasSuffix(text, "]") {
+		return nil, lineError(line, "Missing ] in "+text)
+	}
+	node := Node{Kind: LIST_NODE, Line: line}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	if inner == "" {
+		return &node, nil
+	}
+	for _, item := range strings.Split(inner, ",") {
+		value, err := parseScalar(strings.TrimSpace(item), line)
+		if err != nil {
+			return nil, err
+		}
+		node.Items = append(node.Items, &Node{Kind: SCALAR_NODE, Line: line, Value: value})
+	}
+	return &node, nil
+}
+
+// Function to parse a plain, single quoted or double quoted scalar.
+func parseScalar(text string, line int) (string, error) {
+	switch {
+	case strings.HasPrefix(text, "\""):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return "", lineError(line, "Invalid string "+text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return "", lineError(line, "Invalid string "+text)
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	}
+	return text, nil
+}
@@ -0,0 +1,100 @@
+package grader
+
+import (
+	"strings"
+	"testing"
+)
+
+// Function to write a node in a compact flow form, with mappings in the order of their keys.
+func flowString(node *Node) string {
+	switch node.Kind {
+	case MAP_NODE:
+		var entries []string
+		for _, key := range node.Keys {
+			entries = append(entries, key+": "+flowString(node.Values[key]))
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case LIST_NODE:
+		var items []string
+		for _, item := range node.Items {
+			items = append(items, flowString(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return node.Value
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", "{}"},
+		{"document marker", "---\na: 1\n", "{a: 1}"},
+		{"scalars", "a: 1\nb: x y\nc:\n", "{a: 1, b: x y, c: }"},
+		{"nested mappings", "a:\n  b:\n    c: 1\n  d: 2\ne: 3\n", "{a: {b: {c: 1}, d: 2}, e: 3}"},
+		{"sequence of scalars", "a:\n  - 1\n  - 2\n", "{a: [1, 2]}"},
+		{"sequence at the indentation of its key", "a:\n- 1\n- 2\nb: 3\n", "{a: [1, 2], b: 3}"},
+		{"sequence of mappings", "cases:\n  - name: one\n    x: 1\n  - name: two\n", "{cases: [{name: one, x: 1}, {name: two}]}"},
+		{"item on its own line", "-\n  a: 1\n- b\n", "[{a: 1}, b]"},
+		{"flow sequence", "a: [1, 2, _]\n", "{a: [1, 2, _]}"},
+		{"empty flow sequence", "a: []\n", "{a: []}"},
+		{"flow sequence item", "- [1, 2]\n", "[[1, 2]]"},
+		{"flow sequence with quotes", "a: ['x', \"y z\"]\n", "{a: [x, y z]}"},
+		{"double quoted", "a: \"x: # y\\n\"\n", "{a: x: # y\n}"},
+		{"single quoted", "a: 'it''s'\n", "{a: it's}"},
+		{"quoted key", "\"a b\": 1\n'N': 2\n", "{a b: 1, N: 2}"},
+		{"comments", "# spec\na: 1 # one\n  # indented\nb: x#y\n", "{a: 1, b: x#y}"},
+		{"comment in quotes", "a: '# not a comment' # a comment\n", "{a: # not a comment}"},
+		{"colon without space", "a: 0x10:20\n", "{a: 0x10:20}"},
+		{"windows line endings", "a: 1\r\nb: 2\r\n", "{a: 1, b: 2}"},
+	}
+	for _, test := range tests {
+		node, err := ParseYAML(test.text)
+		if err != nil {
+			t.Errorf("%s: ParseYAML(%q) failed: %v", test.name, test.text, err)
+			continue
+		}
+		if got := flowString(node); got != test.want {
+			t.Errorf("%s: ParseYAML(%q) = %s, want %s", test.name, test.text, got, test.want)
+		}
+	}
+}
+
+func TestParseYAMLLines(t *testing.T) {
+	node, err := ParseYAML("# comment\na: 1\nb:\n  - x\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.KeyLines["a"] != 2 || node.KeyLines["b"] != 3 {
+		t.Errorf("key lines = %v, want a on 2 and b on 3", node.KeyLines)
+	}
+	if line := node.Values["b"].Items[0].Line; line != 4 {
+		t.Errorf("item line = %d, want 4", line)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"tab indentation", "a:\n\tb: 1\n", "line 2: Tabs"},
+		{"missing colon", "a: 1\nb\n", "line 2: Expected key: value"},
+		{"duplicate key", "a: 1\na: 2\n", "line 2: Duplicate key a"},
+		{"unclosed flow sequence", "a: [1, 2\n", "line 1: Missing ]"},
+		{"unclosed double quote", "a: \"x\n", "line 1: Invalid string"},
+		{"unclosed single quote", "a: 'x\n", "line 1: Invalid string"},
+		{"bad indentation", "a:\n    b: 1\n  c: 2\n", "line 3: Unexpected indentation"},
+	}
+	for _, test := range tests {
+		_, err := ParseYAML(test.text)
+		if err == nil {
+			t.Errorf("%s: ParseYAML(%q) succeeded, want an error", test.name, test.text)
+		} else if !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%s: ParseYAML(%q) failed with %q, want %q", test.name, test.text, err.Error(), test.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package hexfile
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Formats of hex files
+const (
+	INTEL_HEX_FORMAT = "Intel HEX"
+	SRECORD_FORMAT   = "S-record"
+)
+
+// Number of data bytes written in each record
+const RECORD_SIZE = 16
+
+// Intel HEX record types
+const (
+	DATA_RECORD             = 0
+	END_OF_FILE_RECORD      = 1
+	EXTENDED_SEGMENT_RECORD = 2
+	START_SEGMENT_RECORD    = 3
+	EXTENDED_LINEAR_RECORD  = 4
+	START_LINEAR_RECORD     = 5
+)
+
+// Block is a run of bytes starting at an address, read from a single line of a file.
+type Block struct {
+	Address int64
+	Data    []byte
+	Line    int
+}
+
+// End is a method to find the address of the last byte of a block.
+func (block Block) End() int64 {
+	return block.Address + int64(len(block.Data)) - 1
+}
+
+// Function to make an error for a line of a file.
+func lineError(fileName string, line int, message string) error {
+	return errors.New(fileName + ":" + strconv.Itoa(line) + ": " + message)
+}
+
+// FormatOf is a function to find the format of a file from its extension:
+// .hex, .ihx and .ihex are Intel HEX, and .srec, .s19, .s28, .s37 and .mot are S-records.
+func FormatOf(fileName string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".hex", ".ihx", ".ihex":
+		return INTEL_HEX_FORMAT, nil
+	case ".srec", ".s19", ".s28", ".s37", ".mot":
+		return SRECORD_FORMAT, nil
+	}
+	return "", errors.New("Unknown hex file extension of " + fileName + ", expected .hex, .ihx, .ihex, .srec, .s19, .s28, .s37 or .mot")
+}
+
+// Read is a function to read the data of an Intel HEX or S-record file, telling them apart by the first record.
+// Every record is checked against its checksum.
+func Read(fileName string) ([]Block, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.New("Error opening file : " + err.Error())
+	}
+	defer file.Close()
+
+	var blocks []Block
+	var base int64
+	isFirst, isSRecord, isEnded := true, false, false
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if isEnded {
+			return nil, lineError(fileName, line, "Record after the end of file record")
+		}
+		if isFirst {
+			isFirst, isSRecord = false, strings.HasPrefix(text, "S")
+		}
+
+		var block *Block
+		if isSRecord {
+			block, isEnded, err = parseSRecord(text)
+		} else {
+			block, isEnded, err = parseIntelRecord(text, &base)
+		}
+		if err != nil {
+			return nil, lineError(fileName, line, err.Error())
+		}
+		if block != nil {
+			block.Line = line
+			blocks = append(blocks, *block)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.New("Error reading file : " + err.Error())
+	}
+	return blocks, nil
+}
+
+// Function to decode the hex digits of a record after its start code.
+func decodeRecord(text string) ([]byte, error) {
+	bytes, err := hex.DecodeString(text)
+	if err != nil || len(bytes) == 0 {
+		return nil, errors.New("Invalid hex digits in record " + text)
+	}
+	return bytes, nil
+}
+
+// Function to parse a line of an Intel HEX file, ":LLAAAATT<data>CC".
+// Extended address records change the base added to the addresses of later data records.
+func parseIntelRecord(text string, base *int64) (*Block, bool, error) {
+	if !strings.HasPrefix(text, ":") {
+		return nil, false, errors.New("Intel HEX records start with ':', found " + text)
+	}
+	bytes, err := decodeRecord(text[1:])
+	if err != nil {
+		return nil, false, err
+	}
+	if len(bytes) < 5 || len(bytes) != int(bytes[0])+5 {
+		return nil, false, errors.New("Record length does not match its byte count in " + text)
+	}
+	var sum byte
+	for _, value := range bytes {
+		sum += value
+	}
+	if sum != 0 {
+		expected := bytes[len(bytes)-1] - sum
+		return nil, false, fmt.Errorf("Checksum is 0x%02X, expected 0x%02X in %s", bytes[len(bytes)-1], expected, text)
+	}
+
+	address := int64(bytes[1])<<8 | int64(bytes[2])
+	data := bytes[4 : len(bytes)-1]
+	switch bytes[3] {
+	case DATA_RECORD:
+		return &Block{Address: *base + address, Data: data}, false, nil
+	case END_OF_FILE_RECORD:
+		return nil, true, nil
+	case EXTENDED_SEGMENT_RECORD, EXTENDED_LINEAR_RECORD:
+		if len(data) != 2 {
+			return nil, false, errors.New("Extended address records hold 2 bytes, found " + text)
+		}
+		*base = int64(data[0])<<8 | int64(data[1])
+		if bytes[3] == EXTENDED_SEGMENT_RECORD {
+			*base <<= 4
+		} else {
+			*base <<= 16
+		}
+		return nil, false, nil
+	case START_SEGMENT_RECORD, START_LINEAR_RECORD:
+		return nil, false, nil
+	}
+	return nil, false, errors.New("Unknown record type " + strconv.Itoa(int(bytes[3])) + " in " + text)
+}
+
+// Function to parse a line of an S-record file, "STLL<address><data>CC".
+// S1, S2 and S3 records hold data at 2, 3 and 4 byte addresses, and S7, S8 and S9 end the file.
+func parseSRecord(text string) (*Block, bool, error) {
+	if len(text) < 2 || text[0] != 'S' || text[1] < '0' || text[1] > '9' {
+		return nil, false, errors.New("S-records start with S and a digit, found " + text)
+	}
+	bytes, err := decodeRecord(text[2:])
+	if err != nil {
+		return nil, false, err
+	}
+	if len(bytes) != int(bytes[0])+1 {
+		return nil, false, errors.New("Record length does not match its byte count in " + text)
+	}
+	var sum byte
+	for _, value := range bytes[:len(bytes)-1] {
+		sum += value
+	}
+	if expected := ^sum; expected != bytes[len(bytes)-1] {
+		return nil, false, fmt.Errorf("Checksum is 0x%02X, expected 0x%02X in %s", bytes[len(bytes)-1], expected, text)
+	}
+
+	addressSize := map[byte]int{'0': 2, '1': 2, '2': 3, '3': 4, '5': 2, '6': 3, '7': 4, '8': 3, '9': 2}[text[1]]
+	if addressSize == 0 {
+		return nil, false, errors.New("Unknown record type S" + string(text[1]) + " in " + text)
+	}
+	if len(bytes) < addressSize+2 {
+		return nil, false, errors.New("Record is too short for its address in " + text)
+	}
+	var address int64
+	for _, value := range bytes[1 : addressSize+1] {
+		address = address<<8 | int64(value)
+	}
+	switch text[1] {
+	case '1', '2', '3':
+		return &Block{Address: address, Data: bytes[addressSize+1 : len(bytes)-1]}, false, nil
+	case '7', '8', '9':
+		return nil, true, nil
+	}
+	// S0 headers and S5, S6 record counts hold no data
+	return nil, false, nil
+}
+
+// Write is a function to write blocks of data to a file, in the format given by its extension.
+// Each record holds at most RECORD_SIZE bytes.
+func Write(fileName string, blocks []Block) error {
+	format, err := FormatOf(fileName)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return errors.New("Error creating file : " + err.Error())
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if format == INTEL_HEX_FORMAT {
+		writeIntelHex(writer, blocks)
+	} else {
+		writeSRecords(writer, blocks)
+	}
+	return writer.Flush()
+}
+
+// Function to split blocks into records of at most RECORD_SIZE bytes that do not cross a 64 KB boundary.
+func splitRecords(blocks []Block) []Block {
+	var records []Block
+	for _, block := range blocks {
+		for start := 0; start < len(block.Data); {
+			address := block.Address + int64(start)
+			size := RECORD_SIZE
+			if boundary := int(0x10000 - address&0xFFFF); size > boundary {
+				size = boundary
+			}
+			if size > len(block.Data)-start {
+				size = len(block.Data) - start
+			}
+			records = append(records, Block{Address: address, Data: block.Data[start : start+size]})
+			start += size
+		}
+	}
+	return records
+}
+
+// Function to write a single record, as hex digits followed by its checksum.
+// Intel HEX checksums are the two's complement of the sum of the bytes, S-record checksums the ones' complement.
+func writeRecord(writer *bufio.Writer, start string, bytes []byte, isSRecord bool) {
+	var sum byte
+	for _, value := range bytes {
+		sum += value
+	}
+	checksum := -sum
+	if isSRecord {
+		checksum = ^sum
+	}
+	fmt.Fprintf(writer, "%s%s%02X\n", start, strings.ToUpper(hex.EncodeToString(bytes)), checksum)
+}
+
+func writeIntelHex(writer *bufio.Writer, blocks []Block) {
+	var base int64
+	for _, record := range splitRecords(blocks) {
+		if upper := record.Address >> 16; upper != base>>16 {
+			base = upper << 16
+			writeRecord(writer, ":", []byte{2, 0, 0, EXTENDED_LINEAR_RECORD, byte(upper >> 8), byte(upper)}, false)
+		}
+		bytes := []byte{byte(len(record.Data)), byte(record.Address >> 8), byte(record.Address), DATA_RECORD}
+		writeRecord(writer, ":", append(bytes, record.Data...), false)
+	}
+	writeRecord(writer, ":", []byte{0, 0, 0, END_OF_FILE_RECORD}, false)
+}
+
+func writeSRecords(writer *bufio.Writer, blocks []Block) {
+	records := splitRecords(blocks)
+	// the smallest address size that holds every address
+	addressSize := 2
+	for _, record := range records {
+		for record.End() >= int64(1)<<uint(8*addressSize) {
+			addressSize++
+		}
+	}
+	dataType := strconv.Itoa(addressSize - 1)
+	endType := strconv.Itoa(11 - addressSize)
+
+	writeRecord(writer, "S0", append([]byte{byte(len("ARMed") + 3), 0, 0}, "ARMed"...), true)
+	for _, record := range records {
+		bytes := []byte{byte(addressSize + len(record.Data) + 1)}
+		for i := addressSize - 1; i >= 0; i-- {
+			bytes = append(bytes, byte(record.Address>>uint(8*i)))
+		}
+		writeRecord(writer, "S"+dataType, append(bytes, record.Data...), true)
+	}
+	if len(records) <= 0xFFFF {
+		writeRecord(writer, "S5", []byte{3, byte(len(records) >> 8), byte(len(records))}, true)
+	}
+	end := []byte{byte(addressSize + 1)}
+	writeRecord(writer, "S"+endType, append(end, make([]byte, addressSize)...), true)
+}
@@ -0,0 +1,153 @@
+package hexfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Function to write the lines of a file into a temporary directory, returning its name.
+func writeLines(t *testing.T, name string, lines ...string) string {
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fileName, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+// Function to collect the bytes of blocks by address, so that blocks split differently compare equal.
+func byteMap(blocks []Block) map[int64]byte {
+	bytes := make(map[int64]byte)
+	for _, block := range blocks {
+		for i, value := range block.Data {
+			bytes[block.Address+int64(i)] = value
+		}
+	}
+	return bytes
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []Block
+	}{
+		{"Intel HEX data", []string{
+			":10010000214601360121470136007EFE09D2190140",
+			":00000001FF",
+		}, []Block{{Address: 0x100, Data: []byte{0x21, 0x46, 0x01, 0x36, 0x01, 0x21, 0x47, 0x01, 0x36, 0x00, 0x7E, 0xFE, 0x09, 0xD2, 0x19, 0x01}, Line: 1}}},
+		{"Intel HEX extended linear address", []string{
+			":020000040001F9",
+			":02000400ABCD82",
+			":00000001FF",
+		}, []Block{{Address: 0x10004, Data: []byte{0xAB, 0xCD}, Line: 2}}},
+		{"Intel HEX extended segment address", []string{
+			":020000021000EC",
+			":01000000AA55",
+			":00000001FF",
+		}, []Block{{Address: 0x10000, Data: []byte{0xAA}, Line: 2}}},
+		{"S-record data", []string{
+			"S00600004844521B",
+			"S1137AF00A0A0D0000000000000000000000000061",
+			"S5030001FB",
+			"S9030000FC",
+		}, []Block{{Address: 0x7AF0, Data: []byte{0x0A, 0x0A, 0x0D, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, Line: 2}}},
+		{"S-record 4 byte address", []string{
+			"S30900010000AABBCCDDE7",
+			"S70500000000FA",
+		}, []Block{{Address: 0x10000, Data: []byte{0xAA, 0xBB, 0xCC, 0xDD}, Line: 1}}},
+	}
+	for _, test := range tests {
+		blocks, err := Read(writeLines(t, "test", test.lines...))
+		if err != nil {
+			t.Errorf("%s: Read failed: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(blocks, test.want) {
+			t.Errorf("%s: Read = %v, want %v", test.name, blocks, test.want)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"Intel HEX bad checksum", []string{":10010000214601360121470136007EFE09D2190141"}, ":1: Checksum is 0x41, expected 0x40"},
+		{"S-record bad checksum", []string{"S1137AF00A0A0D0000000000000000000000000062"}, ":1: Checksum is 0x62, expected 0x61"},
+		{"Intel HEX bad length", []string{":0200000001FF"}, ":1: Record length does not match"},
+		{"S-record bad length", []string{"S1047AF00A"}, ":1: Record length does not match"},
+		{"Intel HEX bad digits", []string{":0G"}, ":1: Invalid hex digits"},
+		{"mixed formats", []string{":00000001FF", "S9030000FC"}, ":2: Record after the end of file record"},
+		{"Intel HEX missing start code", []string{":01000000AA55", "01000000AA55"}, ":2: Intel HEX records start with ':'"},
+		{"Intel HEX unknown type", []string{":00000006FA"}, ":1: Unknown record type 6"},
+		{"S-record unknown type", []string{"S4030000FC"}, ":1: Unknown record type S4"},
+	}
+	for _, test := range tests {
+		_, err := Read(writeLines(t, "test", test.lines...))
+		if err == nil {
+			t.Errorf("%s: Read succeeded, want an error", test.name)
+		} else if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: Read failed with %q, want %q", test.name, err.Error(), test.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := make([]byte, 40)
+	for i := range data {
+		data[i] = byte(3*i + 1)
+	}
+	tests := []struct {
+		name   string
+		blocks []Block
+	}{
+		{"small", []Block{{Address: 0, Data: data[:5]}}},
+		{"several records", []Block{{Address: 0x40, Data: data}, {Address: 0x200, Data: data[:3]}}},
+		{"across 64 KB", []Block{{Address: 0xFFF8, Data: data}}},
+		{"above 16 MB", []Block{{Address: 0x1000000, Data: data[:20]}}},
+	}
+	for _, test := range tests {
+		for _, name := range []string{"test.hex", "test.srec"} {
+			fileName := filepath.Join(t.TempDir(), name)
+			if err := Write(fileName, test.blocks); err != nil {
+				t.Fatalf("%s: Write(%s) failed: %v", test.name, name, err)
+			}
+			blocks, err := Read(fileName)
+			if err != nil {
+				t.Errorf("%s: Read(%s) failed: %v", test.name, name, err)
+				continue
+			}
+			if got, want := byteMap(blocks), byteMap(test.blocks); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %s read back %v, want %v", test.name, name, got, want)
+			}
+			for _, block := range blocks {
+				if len(block.Data) > RECORD_SIZE || block.Address>>16 != block.End()>>16 {
+					t.Errorf("%s: %s has record %v over RECORD_SIZE or a 64 KB boundary", test.name, name, block)
+				}
+			}
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"a.hex":  INTEL_HEX_FORMAT,
+		"a.IHX":  INTEL_HEX_FORMAT,
+		"a.srec": SRECORD_FORMAT,
+		"a.s37":  SRECORD_FORMAT,
+		"a.mot":  SRECORD_FORMAT,
+	}
+	for fileName, want := range tests {
+		if format, err := FormatOf(fileName); err != nil || format != want {
+			t.Errorf("FormatOf(%s) = %q, %v, want %q", fileName, format, err, want)
+		}
+	}
+	if _, err := FormatOf("a.bin"); err == nil {
+		t.Errorf("FormatOf(a.bin) succeeded, want an error")
+	}
+}
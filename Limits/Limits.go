@@ -0,0 +1,158 @@
+package limits
+
+import (
+	Memory "github.com/coderick14/ARMed/Memory"
+	"strconv"
+	"time"
+)
+
+// Exit statuses of a program stopped by a limit.
+// Status 1 is used for programs with errors, and 2 for invalid flags.
+const (
+	INSTRUCTION_LIMIT_STATUS = 3
+	TIME_LIMIT_STATUS        = 4
+	MEMORY_LIMIT_STATUS      = 5
+	OUTPUT_LIMIT_STATUS      = 6
+	INFINITE_LOOP_STATUS     = 7
+)
+
+// Number of instructions between two checks of the wall clock
+const CLOCK_INTERVAL = 1024
+
+// Config holds the limits of a run. A limit of 0 means no limit.
+// Memory is measured in bytes of data memory touched, counted by word.
+type Config struct {
+	MaxInstructions int64
+	MaxTime         time.Duration
+	MaxMemory       int64
+	MaxOutput       int64
+	DetectLoops     bool
+}
+
+// Violation describes why a program was stopped.
+type Violation struct {
+	Status  int
+	Message string
+}
+
+// Struct to remember the state of the machine at the start of a loop
+type snapshot struct {
+	registers [32]int64
+	flags     [4]bool
+	writes    int64
+}
+
+// Monitor stops a program that runs for too long, touches too much memory or prints too much.
+// It can also detect a loop that comes back to its start with every register, flag and
+// memory word unchanged, which can never end.
+type Monitor struct {
+	config       Config
+	start        time.Time
+	instructions int64
+	output       int64
+	touched      map[int64]bool
+	writes       int64
+	loopStarts   map[int64]snapshot
+	loopFrom     int64
+	loopTo       int64
+	violation    *Violation
+}
+
+// NewMonitor is a function to create a monitor for the given limits, starting the clock.
+func NewMonitor(config Config) *Monitor {
+	return &Monitor{
+		config:     config,
+		start:      time.Now(),
+		touched:    make(map[int64]bool),
+		loopStarts: make(map[int64]snapshot),
+		loopFrom:   -1,
+	}
+}
+
+// Reset is a method to forget everything observed so far and restart the clock, for a program loaded again.
+func (monitor *Monitor) Reset() {
+	*monitor = *NewMonitor(monitor.config)
+}
+
+// IsLimited is a method to check if any limit is set.
+func (monitor *Monitor) IsLimited() bool {
+	config := monitor.config
+	return config.MaxInstructions != 0 || config.MaxTime != 0 || config.MaxMemory != 0 || config.MaxOutput != 0 || config.DetectLoops
+}
+
+// AddOutput is a method to count bytes printed while the program runs.
+func (monitor *Monitor) AddOutput(bytes int) {
+	monitor.output += int64(bytes)
+	if monitor.config.MaxOutput != 0 && monitor.output > monitor.config.MaxOutput {
+		monitor.stop(OUTPUT_LIMIT_STATUS, "Output limit of "+strconv.FormatInt(monitor.config.MaxOutput, 10)+" bytes reached")
+	}
+}
+
+// Observe is a method to check the limits after an executed instruction.
+func (monitor *Monitor) Observe(execution *Memory.Execution) {
+	config := monitor.config
+	monitor.instructions++
+	// exactly MaxInstructions run, and a program that ends on the last of them is not stopped
+	if config.MaxInstructions != 0 && monitor.instructions >= config.MaxInstructions && Memory.IsValidPC(execution.NextPC) {
+		monitor.stop(INSTRUCTION_LIMIT_STATUS, "Instruction limit of "+strconv.FormatInt(config.MaxInstructions, 10)+" reached")
+	}
+	if config.MaxTime != 0 && monitor.instructions%CLOCK_INTERVAL == 0 && time.Since(monitor.start) > config.MaxTime {
+		monitor.stop(TIME_LIMIT_STATUS, "Time limit of "+config.MaxTime.String()+" reached")
+	}
+
+	for _, access := range execution.MemoryAccesses {
+		monitor.touched[access.Address/Memory.WORD_SIZE] = true
+		if access.IsWrite && access.OldValue != access.Value {
+			monitor.writes++
+		}
+	}
+	if config.MaxMemory != 0 && int64(len(monitor.touched))*Memory.WORD_SIZE > config.MaxMemory {
+		monitor.stop(MEMORY_LIMIT_STATUS, "Memory limit of "+strconv.FormatInt(config.MaxMemory, 10)+" bytes reached")
+	}
+
+	// a backward branch closes a loop, while calls and returns do not
+	isCall := execution.Mnemonic == "BL" || execution.Mnemonic == "BR"
+	if execution.IsTaken && !isCall && execution.NextPC <= execution.PC {
+		monitor.loopFrom, monitor.loopTo = execution.NextPC, execution.PC
+		if config.DetectLoops {
+			monitor.checkLoop(execution.NextPC)
+		}
+	}
+}
+
+// Method to compare the state of the machine with the last time it was at the start of a loop.
+func (monitor *Monitor) checkLoop(start int64) {
+	var current snapshot
+	for i := range current.registers {
+		current.registers[i] = Memory.GetRegisterValue(uint(i))
+	}
+	current.flags = Memory.GetFlags()
+	current.writes = monitor.writes
+
+	if previous, isKnown := monitor.loopStarts[start]; isKnown && previous == current {
+		monitor.stop(INFINITE_LOOP_STATUS, "Infinite loop, an iteration changed no register, flag or memory word")
+	}
+	monitor.loopStarts[start] = current
+}
+
+// Method to record the first limit that was reached.
+func (monitor *Monitor) stop(status int, message string) {
+	if monitor.violation == nil {
+		monitor.violation = &Violation{Status: status, Message: message}
+	}
+}
+
+// Violation is a method to return the limit that was reached, or nil if none was.
+func (monitor *Monitor) Violation() *Violation {
+	return monitor.violation
+}
+
+// Where is a method to describe the loop the program was last running, as "file:line to file:line",
+// or an empty string if it never branched backwards.
+func (monitor *Monitor) Where() string {
+	if monitor.loopFrom == -1 {
+		return ""
+	}
+	locations := Memory.InstructionMem.Locations
+	return locations[monitor.loopFrom].String() + " to " + locations[monitor.loopTo].String()
+}
@@ -0,0 +1,79 @@
+package limits
+
+import (
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"testing"
+)
+
+// Function to run a program under a monitor until it ends or a limit is reached, returning the instructions run.
+func run(t *testing.T, monitor *Monitor, lines ...string) int64 {
+	var statements []Assembler.Statement
+	for i, line := range lines {
+		statements = append(statements, Assembler.Statement{Text: line, Location: Assembler.Location{File: "test.s", Line: i + 1}})
+	}
+	object, err := Assembler.Assemble(statements, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := Assembler.Link([]*Assembler.Object{object})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Memory.ResetMachine()
+	if err = Memory.LoadProgram(program); err != nil {
+		t.Fatal(err)
+	}
+	Memory.InitRegisters()
+	Memory.AddObserver(monitor)
+	for Memory.IsValidPC(Memory.InstructionMem.PC) && monitor.Violation() == nil {
+		if err = Memory.InstructionMem.ValidateAndExecuteInstruction(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return monitor.instructions
+}
+
+func TestInstructionLimit(t *testing.T) {
+	loop := []string{"loop: ADDI X1, X1, #1", "B loop"}
+	straight := []string{"ADDI X1, X1, #1", "ADDI X1, X1, #1", "ADDI X1, X1, #1"}
+	tests := []struct {
+		name      string
+		lines     []string
+		limit     int64
+		want      int64
+		isStopped bool
+	}{
+		{"loop", loop, 5, 5, true},
+		{"loop with a limit of 1", loop, 1, 1, true},
+		{"program ending at the limit", straight, 3, 3, false},
+		{"program ending before the limit", straight, 4, 3, false},
+		{"program longer than the limit", straight, 2, 2, true},
+	}
+	for _, test := range tests {
+		monitor := NewMonitor(Config{MaxInstructions: test.limit})
+		instructions := run(t, monitor, test.lines...)
+		violation := monitor.Violation()
+		if instructions != test.want || (violation != nil) != test.isStopped {
+			t.Errorf("%s: ran %d instructions with violation %v, want %d with a violation %t", test.name, instructions, violation, test.want, test.isStopped)
+		}
+		if violation != nil && violation.Status != INSTRUCTION_LIMIT_STATUS {
+			t.Errorf("%s: status %d, want %d", test.name, violation.Status, INSTRUCTION_LIMIT_STATUS)
+		}
+	}
+}
+
+func TestDetectLoops(t *testing.T) {
+	monitor := NewMonitor(Config{DetectLoops: true, MaxInstructions: 1000})
+	run(t, monitor, "loop: ADDI X1, XZR, #1", "B loop")
+	if violation := monitor.Violation(); violation == nil || violation.Status != INFINITE_LOOP_STATUS {
+		t.Errorf("violation = %v, want an infinite loop", violation)
+	}
+
+	monitor = NewMonitor(Config{DetectLoops: true, MaxInstructions: 1000})
+	run(t, monitor, "loop: ADDI X1, X1, #1", "B loop")
+	if violation := monitor.Violation(); violation == nil || violation.Status != INSTRUCTION_LIMIT_STATUS {
+		t.Errorf("violation = %v, want the instruction limit for a loop that changes X1", violation)
+	}
+}
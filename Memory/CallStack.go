@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"fmt"
+)
+
+// Frame is a call made with BL that has not returned yet.
+type Frame struct {
+	Function string
+	CallPC   int64
+	SP       int64
+}
+
+// CallStack holds the calls that have not returned yet, the innermost last
+var CallStack []Frame
+
+// Function to record a call made by the BL at callPC.
+func pushFrame(function string, callPC int64) {
+	CallStack = append(CallStack, Frame{Function: function, CallPC: callPC, SP: registers[SP]})
+}
+
+// Function to record a branch to a register. If it goes back to the instruction after
+// a call, that call and every call made after it have returned.
+func popFrames(target int64) {
+	for i := len(CallStack) - 1; i >= 0; i-- {
+		if CallStack[i].CallPC+INCREMENT == target {
+			CallStack = CallStack[:i]
+			return
+		}
+	}
+}
+
+// ShowBacktrace is a function to print the calls that led to the current instruction, innermost first.
+// Each frame shows the function called, the line of the call and the value of SP when it was made.
+func ShowBacktrace() {
+	fmt.Println("Backtrace :")
+	if IsValidPC(InstructionMem.PC) {
+		fmt.Println("    at", InstructionMem.Locations[InstructionMem.PC].String())
+	}
+	for i := len(CallStack) - 1; i >= 0; i-- {
+		frame := CallStack[i]
+		fmt.Printf("#%d  %s, called from %s, SP at entry = %d\n", len(CallStack)-1-i, frame.Function, InstructionMem.Locations[frame.CallPC].String(), frame.SP)
+	}
+	fmt.Printf("#%d  main\n", len(CallStack))
+}
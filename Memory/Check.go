@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"errors"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	"strconv"
+	"strings"
+)
+
+// CheckInstructions is a function to check the syntax of every instruction before the program runs.
+// All errors are returned together, each with the location of its statement.
+func CheckInstructions(statements []Assembler.Statement) Assembler.ErrorList {
+	var errorList Assembler.ErrorList
+	for _, statement := range statements {
+		if isEmptyInstruction(statement.Text) {
+			continue
+		}
+		instruction, err := decodeInstruction(statement.Text)
+		if err == nil {
+			err = instruction.checkSyntax()
+		}
+		if err != nil {
+			errorList = append(errorList, errors.New(statement.Location.String()+": "+err.Error()))
+		}
+	}
+	return errorList
+}
+
+// CheckProgram is a function to validate a linked program before its first instruction executes.
+// It returns warnings about unreachable code and about functions that are entered or left
+// by falling through, and an error listing every invalid instruction and undefined label.
+func CheckProgram(program *Assembler.Object) (Assembler.ErrorList, error) {
+	errorList := CheckInstructions(program.Text)
+
+	labels := make(map[string]int64)
+	for _, symbol := range program.Symbols {
+		if symbol.Section == Assembler.TEXT_SECTION {
+			labels[symbol.Name] = symbol.Value
+		}
+	}
+	for _, statement := range program.Text {
+		for _, label := range labelOperands(statement.Text) {
+			if _, isDefined := labels[label]; !isDefined {
+				errorList = append(errorList, errors.New(statement.Location.String()+": Undefined label "+label+" in "+statement.Text))
+			}
+		}
+	}
+	if len(errorList) != 0 {
+		return nil, errorList
+	}
+
+	return checkControlFlow(program, labels), nil
+}
+
+// Function to find the label operands of an instruction.
+func labelOperands(inst string) []string {
+	var names []string
+	instruction, err := Assembler.ParseInstruction(inst)
+	if err != nil {
+		return names
+	}
+	for _, operand := range instruction.Operands {
+		if operand.Kind == Assembler.LABEL_OPERAND {
+			names = append(names, operand.Expression)
+		}
+	}
+	return names
+}
+
+// Function to find the instructions that can execute right after an instruction.
+// Calls are assumed to return to the next instruction, and BR ends the current path.
+// The second result tells if the next instruction is one of them.
+func successors(inst string, PC int64, labels map[string]int64) ([]int64, bool) {
+	if isEmptyInstruction(inst) {
+		return []int64{PC + INCREMENT}, true
+	}
+	var targets []int64
+	for _, label := range labelOperands(inst) {
+		targets = append(targets, labels[label])
+	}
+
+	mnemonic := strings.SplitN(inst, " ", 2)[0]
+	switch {
+	case mnemonic == "B":
+		return targets, false
+	case mnemonic == "BR":
+		return nil, false
+	case mnemonic == "BL" || mnemonic == "CBZ" || mnemonic == "CBNZ" || strings.HasPrefix(mnemonic, "B."):
+		return append(targets, PC+INCREMENT), true
+	}
+	return []int64{PC + INCREMENT}, true
+}
+
+// Function to find every instruction that can be reached from the given ones.
+// Index len(program.Text) stands for running past the end of the program.
+func reachableFrom(program *Assembler.Object, labels map[string]int64, starts ...int64) []bool {
+	reached := make([]bool, len(program.Text)+1)
+	pending := starts
+	for len(pending) != 0 {
+		PC := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if PC < 0 || PC > int64(len(program.Text)) || reached[PC] {
+			continue
+		}
+		reached[PC] = true
+		if PC < int64(len(program.Text)) {
+			next, _ := successors(program.Text[PC].Text, PC, labels)
+			pending = append(pending, next...)
+		}
+	}
+	return reached
+}
+
+// Function to look for unreachable code, for code that falls through into a function
+// and for functions that can run past the end of the program without returning.
+func checkControlFlow(program *Assembler.Object, labels map[string]int64) Assembler.ErrorList {
+	var warnings Assembler.ErrorList
+	warn := func(PC int64, message string) {
+		location := program.Text[PC].Location
+		warnings = append(warnings, errors.New(location.String()+": Warning : "+message))
+	}
+
+	if len(program.Text) == 0 {
+		return warnings
+	}
+	reached := reachableFrom(program, labels, program.Entry)
+
+	// unreachable instructions are reported once per block
+	for PC := int64(0); PC < int64(len(program.Text)); PC++ {
+		if reached[PC] {
+			continue
+		}
+		start, count := PC, 0
+		for ; PC < int64(len(program.Text)) && !reached[PC]; PC++ {
+			if !isEmptyInstruction(program.Text[PC].Text) {
+				count++
+			}
+		}
+		if count != 0 {
+			warn(start, "Unreachable code, "+strconv.Itoa(count)+" instruction(s) can never execute")
+		}
+	}
+
+	instructions := make([]string, len(program.Text))
+	for i, statement := range program.Text {
+		instructions[i] = statement.Text
+	}
+	functions := findFunctions(instructions, labels)
+
+	for PC := int64(0); PC < int64(len(program.Text)); PC++ {
+		name, isFunction := functions[PC]
+		if !isFunction {
+			continue
+		}
+		if PC > 0 && reached[PC-1] {
+			if _, fallsThrough := successors(program.Text[PC-1].Text, PC-1, labels); fallsThrough {
+				warn(PC-1, "Execution falls through into function "+name)
+			}
+		}
+		if reachableFrom(program, labels, PC)[len(program.Text)] {
+			warn(PC, "Function "+name+" can run past the end of the program without returning")
+		}
+	}
+
+	return warnings
+}
+
+// Function to find the functions of a program, which are the targets of BL.
+// Returns the name of each function by the number of its first instruction.
+func findFunctions(instructions []string, labels map[string]int64) map[int64]string {
+	functions := make(map[int64]string)
+	for _, instruction := range instructions {
+		if strings.HasPrefix(instruction, "BL ") {
+			label := labelOperands(instruction)[0]
+			functions[labels[label]] = label
+		}
+	}
+	return functions
+}
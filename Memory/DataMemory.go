@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"errors"
 	"fmt"
 	color "github.com/fatih/color"
 	tablewriter "github.com/olekukonko/tablewriter"
@@ -87,12 +88,104 @@ func (dataMemory *DataMemory) write(address uint64, value int32) {
 	dataMemory.Unlock()
 }
 
+// Method to load a value of 1, 2 or 4 bytes from a byte address.
+// Words are big-endian, so the first byte of a word holds its top 8 bits.
+// The value is returned without sign extension.
+func (dataMemory *DataMemory) load(address int64, size int64) int64 {
+	shift := uint(8 * (WORD_SIZE - size - address%WORD_SIZE))
+	mask := int64(1)<<uint(8*size) - 1
+	word := int64(uint32(dataMemory.read(uint64(address / WORD_SIZE))))
+	value := (word >> shift) & mask
+
+	traceMemoryAccess(MemoryAccess{Address: address, Size: size, Value: value})
+	return value
+}
+
+// Method to store the lowest 1, 2 or 4 bytes of a value at a byte address.
+// The other bytes of the word are left unchanged.
+func (dataMemory *DataMemory) store(address int64, size int64, value int64) {
+	shift := uint(8 * (WORD_SIZE - size - address%WORD_SIZE))
+	mask := int64(1)<<uint(8*size) - 1
+	index := uint64(address / WORD_SIZE)
+	word := int64(uint32(dataMemory.read(index)))
+	oldValue := (word >> shift) & mask
+	word = (word &^ (mask << shift)) | ((value & mask) << shift)
+	dataMemory.write(index, int32(word))
+
+	traceMemoryAccess(MemoryAccess{Address: address, Size: size, IsWrite: true, OldValue: oldValue, Value: value & mask})
+}
+
+// Function to check that an access of the given number of bytes lies inside data memory.
+func isValidAddress(address int64, size int64) bool {
+	return address >= 0 && address+size <= MEMORY_SIZE*WORD_SIZE
+}
+
+// GetRegisterValue is a function to read a register without recording the access.
+func GetRegisterValue(registerIndex uint) int64 {
+	return registers[registerIndex]
+}
+
+// RegisterName is a function to find the name of a register, such as X9, SP or XZR.
+func RegisterName(index int) string {
+	switch index {
+	case SP:
+		return "SP"
+	case 29:
+		return "FP"
+	case LR:
+		return "LR"
+	case XZR:
+		return "XZR"
+	}
+	return "X" + strconv.Itoa(index)
+}
+
+// GetMemoryWord is a function to read a word of data memory without recording the access.
+func GetMemoryWord(index int64) int32 {
+	return dataMemory.read(uint64(index))
+}
+
+// SetMemoryWord is a function to write a word of data memory without recording the access.
+func SetMemoryWord(index int64, value int32) {
+	dataMemory.write(uint64(index), value)
+}
+
+// SetMemoryBytes is a function to copy bytes into data memory from a byte address, without recording the accesses.
+func SetMemoryBytes(address int64, data []byte) error {
+	if !isValidAddress(address, int64(len(data))) {
+		return errors.New(strconv.Itoa(len(data)) + " bytes at address " + strconv.FormatInt(address, 10) + " do not fit in data memory")
+	}
+	for i, value := range data {
+		dataMemory.store(address+int64(i), 1, int64(value))
+	}
+	return nil
+}
+
+// SetRegisterValue is a function to write a register without recording the access.
+// Writes to XZR are discarded.
+func SetRegisterValue(registerIndex uint, value int64) {
+	if registerIndex != XZR {
+		registers[registerIndex] = value
+	}
+}
+
+// GetFlags is a function to return the condition flags N, Z, V and C.
+func GetFlags() [4]bool {
+	return [4]bool{flagNegative, flagZero, flagOverflow, flagCarry}
+}
+
 // Function to read from register and return its value.
 func getRegisterValue(registerIndex uint) int64 {
+	traceRegisterRead(registerIndex)
 	return registers[registerIndex]
 }
 
 // Function to write to register.
+// Writes to XZR are discarded.
 func setRegisterValue(registerIndex uint, value int64) {
+	if registerIndex == XZR {
+		return
+	}
+	traceRegisterWrite(registerIndex, registers[registerIndex], value)
 	registers[registerIndex] = value
 }
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"strings"
+)
+
+// RegisterWrite is a change made to a register by an instruction.
+type RegisterWrite struct {
+	Register uint
+	OldValue int64
+	NewValue int64
+}
+
+// MemoryAccess is a read or write of data memory made by an instruction.
+// Address is a byte address and Size the number of bytes accessed.
+type MemoryAccess struct {
+	Address  int64
+	Size     int64
+	IsWrite  bool
+	OldValue int64
+	Value    int64
+}
+
+// Execution describes what an executed instruction did.
+// It is filled in while the instruction runs, from the registers and memory it accesses.
+type Execution struct {
+	PC               int64
+	NextPC           int64
+	Instruction      string
+	Mnemonic         string
+	RegistersRead    []uint
+	RegistersWritten []RegisterWrite
+	MemoryAccesses   []MemoryAccess
+	SetsFlags        bool
+	ReadsFlags       bool
+	IsBranch         bool
+	IsConditional    bool
+	IsTaken          bool
+	Target           int64
+}
+
+// Classes of instructions
+const (
+	ALU_CLASS              = "ALU"
+	LOAD_CLASS             = "load"
+	STORE_CLASS            = "store"
+	BRANCH_TAKEN_CLASS     = "branch taken"
+	BRANCH_NOT_TAKEN_CLASS = "branch not taken"
+)
+
+// Classes lists the instruction classes in the order they are reported
+var Classes = []string{ALU_CLASS, LOAD_CLASS, STORE_CLASS, BRANCH_TAKEN_CLASS, BRANCH_NOT_TAKEN_CLASS}
+
+// Class is a method to find the class of an executed instruction.
+// Every instruction that neither accesses memory nor branches counts as ALU.
+func (execution *Execution) Class() string {
+	if len(execution.MemoryAccesses) != 0 {
+		if execution.MemoryAccesses[0].IsWrite {
+			return STORE_CLASS
+		}
+		return LOAD_CLASS
+	}
+	if execution.IsBranch {
+		if execution.IsTaken {
+			return BRANCH_TAKEN_CLASS
+		}
+		return BRANCH_NOT_TAKEN_CLASS
+	}
+	return ALU_CLASS
+}
+
+// Observer is implemented by models that follow the execution of a program, such as the pipeline.
+type Observer interface {
+	// Called after every executed instruction
+	Observe(execution *Execution)
+}
+
+// LastExecution describes the most recently executed instruction
+var LastExecution Execution
+
+// ExecutedInstructions counts the instructions that ran to the end since the machine was reset.
+// Statements that only hold a label are not counted, as they never reach the observers.
+var ExecutedInstructions int64
+
+var observers []Observer
+
+var isTracing bool
+
+// AddObserver is a function to have an observer notified after every executed instruction.
+func AddObserver(observer Observer) {
+	observers = append(observers, observer)
+}
+
+// Function to start recording the execution of an instruction.
+func beginExecution(PC int64, instruction string) {
+	mnemonic := strings.SplitN(instruction, " ", 2)[0]
+	LastExecution = Execution{
+		PC:            PC,
+		Instruction:   instruction,
+		Mnemonic:      mnemonic,
+		SetsFlags:     mnemonic == "ADDS" || mnemonic == "SUBS" || mnemonic == "ADDIS" || mnemonic == "SUBIS" || mnemonic == "ANDS" || mnemonic == "ANDIS",
+		ReadsFlags:    strings.HasPrefix(mnemonic, "B."),
+		IsBranch:      mnemonic == "B" || mnemonic == "BL" || mnemonic == "BR" || mnemonic == "CBZ" || mnemonic == "CBNZ" || strings.HasPrefix(mnemonic, "B."),
+		IsConditional: mnemonic == "CBZ" || mnemonic == "CBNZ" || strings.HasPrefix(mnemonic, "B."),
+	}
+	isTracing = true
+}
+
+// Function to finish the record of an instruction and pass it to the observers.
+// Unconditional branches are always taken.
+func endExecution(nextPC int64) {
+	isTracing = false
+	LastExecution.NextPC = nextPC
+	if LastExecution.IsBranch && !LastExecution.IsConditional {
+		LastExecution.IsTaken = true
+		LastExecution.Target = nextPC
+	}
+	ExecutedInstructions++
+	for _, observer := range observers {
+		observer.Observe(&LastExecution)
+	}
+}
+
+// Function to record a branch decision of a conditional branch.
+func traceBranch(isTaken bool, target int64) {
+	LastExecution.IsTaken = isTaken
+	LastExecution.Target = target
+}
+
+// Function to record that a register was read, once per instruction.
+func traceRegisterRead(registerIndex uint) {
+	if !isTracing {
+		return
+	}
+	for _, register := range LastExecution.RegistersRead {
+		if register == registerIndex {
+			return
+		}
+	}
+	LastExecution.RegistersRead = append(LastExecution.RegistersRead, registerIndex)
+}
+
+// Function to record a change of a register.
+func traceRegisterWrite(registerIndex uint, oldValue int64, newValue int64) {
+	if isTracing {
+		LastExecution.RegistersWritten = append(LastExecution.RegistersWritten, RegisterWrite{registerIndex, oldValue, newValue})
+	}
+}
+
+// Function to record an access to data memory.
+func traceMemoryAccess(access MemoryAccess) {
+	if isTracing {
+		LastExecution.MemoryAccesses = append(LastExecution.MemoryAccesses, access)
+	}
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"errors"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	"strconv"
+	"strings"
+)
+
+// Struct to describe the values an immediate field of an instruction can hold
+type immediateField struct {
+	description string
+	minimum     int64
+	maximum     int64
+}
+
+// Immediate fields of LEGv8 instructions.
+// ADDI and SUBI accept negative constants, which add or subtract the magnitude instead.
+var (
+	arithmeticImmediate         = immediateField{"12-bit immediate", -4095, 4095}
+	unsignedArithmeticImmediate = immediateField{"12-bit unsigned immediate", 0, 4095}
+	logicalImmediate            = immediateField{"12-bit unsigned immediate", 0, 4095}
+	addressOffset               = immediateField{"9-bit signed offset", -256, 255}
+	moveImmediate               = immediateField{"16-bit unsigned immediate", 0, 65535}
+	shiftAmount                 = immediateField{"6-bit shift amount", 0, 63}
+)
+
+// Function to evaluate the constant expression of an immediate operand, with or without '#',
+// and check that its value fits in the field of the instruction.
+func parseImmediate(operand string, field immediateField, inst string) (int64, error) {
+	expression := strings.TrimPrefix(strings.TrimSpace(operand), "#")
+	value, err := Assembler.Evaluate(expression, nil)
+	if err != nil {
+		return 0, errors.New(err.Error() + " in " + inst)
+	}
+	if value < field.minimum || value > field.maximum {
+		return 0, errors.New("Immediate " + strconv.FormatInt(value, 10) + " does not fit in the " + field.description +
+			" (" + strconv.FormatInt(field.minimum, 10) + " to " + strconv.FormatInt(field.maximum, 10) + ") of " + inst)
+	}
+	return value, nil
+}
+
+// Function to check an immediate before the program runs.
+// Expressions that still use symbols are checked once the linker has replaced them by their values.
+func checkImmediate(expression string, field immediateField, inst string) error {
+	if Assembler.UsesSymbols(expression) {
+		return nil
+	}
+	_, err := parseImmediate(expression, field, inst)
+	return err
+}
+
+// Function to evaluate the shift of a move instruction, written either as a multiple
+// of 16 bits (0 to 3) or in bits (0, 16, 32 or 48).
+func parseMoveShift(expression string, inst string) (uint, error) {
+	shift, err := Assembler.Evaluate(expression, nil)
+	if err != nil {
+		return 0, errors.New(err.Error() + " in " + inst)
+	}
+	switch shift {
+	case 0, 1, 2, 3:
+		return uint(shift), nil
+	case 16, 32, 48:
+		return uint(shift / 16), nil
+	}
+	return 0, errors.New("Invalid shift " + strconv.FormatInt(shift, 10) + ", expected 0, 16, 32 or 48 in " + inst)
+}
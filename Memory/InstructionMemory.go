@@ -3,6 +3,7 @@ package memory
 import (
 	"errors"
 	ALU "github.com/coderick14/ARMed/ALU"
+	Assembler "github.com/coderick14/ARMed/Assembler"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,6 +13,8 @@ import (
 type InstructionMemory struct {
 	PC           int64
 	Instructions []string
+	Aliases      []string
+	Locations    []Assembler.Location
 	Labels       map[string]int64
 }
 
@@ -36,6 +39,14 @@ func (instructionMemory *InstructionMemory) updatePC(offset ...int64) {
 	}
 }
 
+// Describe is a method to show an instruction together with the pseudo-instruction it was expanded from.
+func (instructionMemory *InstructionMemory) Describe(PC int64) string {
+	if instructionMemory.Aliases[PC] != "" {
+		return instructionMemory.Aliases[PC] + " => " + instructionMemory.Instructions[PC]
+	}
+	return instructionMemory.Instructions[PC]
+}
+
 // IsValidPC is a function to check if program counter is valid.
 func IsValidPC(PC int64) bool {
 	isValidPC := PC >= 0 && PC < int64(len(InstructionMem.Instructions))
@@ -47,21 +58,40 @@ func isEmptyInstruction(currentInstruction string) bool {
 	return len(currentInstruction) == 0
 }
 
-// ExtractLabels is a method to extract labels from instructions.
-func (instructionMemory *InstructionMemory) ExtractLabels() {
-
-	labelRegex, _ := regexp.Compile("^([a-zA-Z][[:alnum:]]*)[[:space:]]*:")
-	for counter, currentInstruction := range instructionMemory.Instructions {
-		if labelRegex.MatchString(currentInstruction) {
+// ResetMachine is a function to clear instruction memory, data memory, registers, flags
+// and the call stack, so that a program can be loaded again.
+func ResetMachine() {
+	InstructionMem = InstructionMemory{Labels: make(map[string]int64)}
+	for i := range dataMemory.Memory {
+		dataMemory.Memory[i] = 0
+	}
+	registers = [32]int64{}
+	buffer = [32]int64{}
+	flagNegative, flagZero, flagOverflow, flagCarry = false, false, false, false
+	CallStack = nil
+	ExecutedInstructions = 0
+}
 
-			indexColon := strings.Index(currentInstruction, ":")
-			labelName := strings.TrimSpace(currentInstruction[:indexColon])
-			currentInstruction = strings.TrimSpace(currentInstruction[indexColon+1:])
-			instructionMemory.Labels[labelName] = int64(counter)
-			instructionMemory.Instructions[counter] = currentInstruction
+// LoadProgram is a function to fill instruction and data memory from a linked program.
+func LoadProgram(program *Assembler.Object) error {
+	if len(program.Data) > MEMORY_SIZE-STACK_SIZE {
+		return errors.New("Data section of " + strconv.Itoa(len(program.Data)) + " words does not fit in data memory")
+	}
 
+	for _, statement := range program.Text {
+		InstructionMem.Instructions = append(InstructionMem.Instructions, statement.Text)
+		InstructionMem.Aliases = append(InstructionMem.Aliases, statement.Alias)
+		InstructionMem.Locations = append(InstructionMem.Locations, statement.Location)
+	}
+	for _, symbol := range program.Symbols {
+		if symbol.Section == Assembler.TEXT_SECTION {
+			InstructionMem.Labels[symbol.Name] = symbol.Value
 		}
 	}
+	copy(dataMemory.Memory, program.Data)
+	InstructionMem.PC = program.Entry
+
+	return nil
 }
 
 // ValidateAndExecuteInstruction is a method to check instruction type, perform syntax analysis, parse the statement and execute it
@@ -75,170 +105,164 @@ func (instructionMemory *InstructionMemory) ValidateAndExecuteInstruction() erro
 		return nil
 	}
 
-	var err error
+	currentInstructionObject, err := decodeInstruction(currentInstruction)
+	if err != nil {
+		return err
+	}
+
+	beginExecution(instructionMemory.PC, currentInstruction)
+	err = executeInstruction(currentInstructionObject)
+	if err != nil {
+		isTracing = false
+		return err
+	}
+	endExecution(instructionMemory.PC)
+	return nil
+}
+
+// Function to create the object of the right instruction type for a statement
+func decodeInstruction(currentInstruction string) (Instruction, error) {
+	var instruction Instruction
 
 	if strings.HasPrefix(currentInstruction, "ADD ") {
 
-		currentInstructionObject := AddInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &AddInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "SUB ") {
 
-		currentInstructionObject := SubInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &SubInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "MUL ") {
 
-		currentInstructionObject := MulInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &MulInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "ADDI ") {
 
-		currentInstructionObject := AddImmediateInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &AddImmediateInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "SUBI ") {
 
-		currentInstructionObject := SubImmediateInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &SubImmediateInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "ADDS ") {
 
-		currentInstructionObject := AddAndSetFlagsInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &AddAndSetFlagsInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "SUBS ") {
 
-		currentInstructionObject := SubAndSetFlagsInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &SubAndSetFlagsInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "ADDIS ") {
 
-		currentInstructionObject := AddImmediateAndSetFlagsInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &AddImmediateAndSetFlagsInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "SUBIS ") {
 
-		currentInstructionObject := SubImmediateAndSetFlagsInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &SubImmediateAndSetFlagsInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "LDUR ") {
 
-		currentInstructionObject := LoadInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &LoadInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "STUR ") {
 
-		currentInstructionObject := StoreInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &StoreInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "LDURH ") {
 
-		currentInstructionObject := LoadHalfInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &LoadHalfInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "STURH ") {
 
-		currentInstructionObject := StoreHalfInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &StoreHalfInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "LDURB ") {
 
-		currentInstructionObject := LoadByteInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &LoadByteInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "STURB ") {
 
-		currentInstructionObject := StoreByteInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &StoreByteInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "MOVZ ") {
 
-		currentInstructionObject := MoveWithZeroInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &MoveWithZeroInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "MOVK ") {
 
-		currentInstructionObject := MoveWithKeepInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &MoveWithKeepInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "AND ") {
 
-		currentInstructionObject := AndInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &AndInstruction{inst: currentInstruction}
+
+	} else if strings.HasPrefix(currentInstruction, "ANDS ") {
+
+		instruction = &AndAndSetFlagsInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "ORR ") {
 
-		currentInstructionObject := OrInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &OrInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "EOR ") {
 
-		currentInstructionObject := ExclusiveOrInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &ExclusiveOrInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "ANDI ") {
 
-		currentInstructionObject := AndImmediateInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &AndImmediateInstruction{inst: currentInstruction}
+
+	} else if strings.HasPrefix(currentInstruction, "ANDIS ") {
+
+		instruction = &AndImmediateAndSetFlagsInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "ORRI ") {
 
-		currentInstructionObject := OrImmediateInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &OrImmediateInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "EORI ") {
 
-		currentInstructionObject := ExclusiveOrImmediateInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &ExclusiveOrImmediateInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "LSL ") {
 
-		currentInstructionObject := LeftShiftInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &LeftShiftInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "LSR ") {
 
-		currentInstructionObject := RightShiftInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &RightShiftInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "CBZ ") {
 
-		currentInstructionObject := BranchOnZeroInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &BranchOnZeroInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "CBNZ ") {
 
-		currentInstructionObject := BranchOnNonZeroInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &BranchOnNonZeroInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "B.") {
 
-		currentInstructionObject := ConditionalBranchInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &ConditionalBranchInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "B ") {
 
-		currentInstructionObject := BranchInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &BranchInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "BR ") {
 
-		currentInstructionObject := BranchToRegisterInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &BranchToRegisterInstruction{inst: currentInstruction}
 
 	} else if strings.HasPrefix(currentInstruction, "BL ") {
 
-		currentInstructionObject := BranchWithLinkInstruction{inst: currentInstruction}
-		err = executeInstruction(&currentInstructionObject)
+		instruction = &BranchWithLinkInstruction{inst: currentInstruction}
 
 	} else {
 
-		err = errors.New("Invalid instruction type in " + currentInstruction)
+		return nil, errors.New("Invalid instruction type in " + currentInstruction)
 
 	}
 
-	return err
+	return instruction, nil
 }
 
 // All instructions implement the Instruction interface
@@ -268,6 +292,15 @@ func executeInstruction(currentInstruction Instruction) error {
 	return nil
 }
 
+// Function to set condition codes after a logical operation.
+// Logical operations never overflow or carry.
+func setLogicalFlags(result int64) {
+	flagNegative = result < 0
+	flagZero = result == 0
+	flagOverflow = false
+	flagCarry = false
+}
+
 /*
 INSTRUCTION : ADDITION
 
@@ -282,35 +315,14 @@ type AddInstruction struct {
 }
 
 func (instruction *AddInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^ADD X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *AddInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = XZR
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
@@ -335,35 +347,14 @@ type SubInstruction struct {
 }
 
 func (instruction *SubInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^SUB X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *SubInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = XZR
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
@@ -388,35 +379,14 @@ type MulInstruction struct {
 }
 
 func (instruction *MulInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^MUL X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *MulInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = XZR
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
@@ -437,64 +407,55 @@ type AddImmediateInstruction struct {
 	inst     string
 	reg1     uint
 	reg2     uint
-	constant uint
+	constant int64
 }
 
 func (instruction *AddImmediateInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^ADDI ((X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]))|(SP, SP)), #(0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, arithmeticImmediate, instruction.inst)
 }
 
 func (instruction *AddImmediateInstruction) parse() error {
-	statement := instruction.inst
-
-	// if instruction updates stack pointer
-	if strings.Index(statement, "SP") != -1 {
-		indexHash := strings.Index(statement, "#")
-		constant, _ := strconv.Atoi(statement[indexHash+1:])
-		instruction.reg1 = SP
-		instruction.reg2 = SP
-		instruction.constant = uint(constant)
-
-		address := getRegisterValue(instruction.reg2) + int64(instruction.constant)
-		if address > MEMORY_SIZE*WORD_SIZE {
-			return errors.New("Stack underflow error in : " + instruction.inst)
-		}
-
-		return nil
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, arithmeticImmediate, instruction.inst)
+	if err != nil {
+		return err
 	}
 
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = XZR
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
-	}
-	indexHash = strings.Index(statement, "#")
-	constant, _ := strconv.Atoi(statement[indexHash+1:])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.constant = constant
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.constant = uint(constant)
+	// if instruction updates stack pointer
+	if instruction.reg1 == SP {
+		address := getRegisterValue(instruction.reg2) + instruction.constant
+		return checkStackPointer(address, instruction.inst)
+	}
 
 	return nil
 }
 
 func (instruction *AddImmediateInstruction) execute() {
-	result := ALU.Adder(getRegisterValue(instruction.reg2), int64(instruction.constant))
+	result := ALU.Adder(getRegisterValue(instruction.reg2), instruction.constant)
 	setRegisterValue(instruction.reg1, result)
 	InstructionMem.updatePC()
 }
 
+// Function to check that a new stack pointer stays within the stack.
+// Immediates can be negative, so ADDI and SUBI can move the stack pointer either way.
+func checkStackPointer(address int64, inst string) error {
+	if address < (MEMORY_SIZE-STACK_SIZE)*WORD_SIZE {
+		return errors.New("Stack overflow error in : " + inst)
+	}
+	if address > MEMORY_SIZE*WORD_SIZE {
+		return errors.New("Stack underflow error in : " + inst)
+	}
+	return nil
+}
+
 /*
 INSTRUCTION : SUB IMMEDIATE
 
@@ -505,60 +466,39 @@ type SubImmediateInstruction struct {
 	inst     string
 	reg1     uint
 	reg2     uint
-	constant uint
+	constant int64
 }
 
 func (instruction *SubImmediateInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^SUBI ((X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]))|(SP, SP)), #(0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, arithmeticImmediate, instruction.inst)
 }
 
 func (instruction *SubImmediateInstruction) parse() error {
-	statement := instruction.inst
-
-	// if instruction updates stack pointer
-	if strings.Index(statement, "SP") != -1 {
-		indexHash := strings.Index(statement, "#")
-		constant, _ := strconv.Atoi(statement[indexHash+1:])
-		instruction.reg1 = SP
-		instruction.reg2 = SP
-		instruction.constant = uint(constant)
-
-		address := getRegisterValue(instruction.reg2) + int64(instruction.constant)
-		if address < (MEMORY_SIZE-STACK_SIZE)*WORD_SIZE {
-			return errors.New("Stack overflow error in : " + instruction.inst)
-		}
-
-		return nil
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, arithmeticImmediate, instruction.inst)
+	if err != nil {
+		return err
 	}
 
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = XZR
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
-	}
-	indexHash = strings.Index(statement, "#")
-	constant, _ := strconv.Atoi(statement[indexHash+1:])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.constant = constant
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.constant = uint(constant)
+	// if instruction updates stack pointer
+	if instruction.reg1 == SP {
+		address := getRegisterValue(instruction.reg2) - instruction.constant
+		return checkStackPointer(address, instruction.inst)
+	}
 
 	return nil
 }
 
 func (instruction *SubImmediateInstruction) execute() {
-	result := ALU.Adder(getRegisterValue(instruction.reg2), -int64(instruction.constant))
+	result := ALU.Adder(getRegisterValue(instruction.reg2), -instruction.constant)
 	setRegisterValue(instruction.reg1, result)
 	InstructionMem.updatePC()
 }
@@ -579,35 +519,14 @@ type AddAndSetFlagsInstruction struct {
 }
 
 func (instruction *AddAndSetFlagsInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^ADDS X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *AddAndSetFlagsInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = XZR
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
@@ -667,41 +586,20 @@ type SubAndSetFlagsInstruction struct {
 }
 
 func (instruction *SubAndSetFlagsInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^SUBS X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *SubAndSetFlagsInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = XZR
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
 
 func (instruction *SubAndSetFlagsInstruction) execute() {
-	result := ALU.Adder(getRegisterValue(instruction.reg2), getRegisterValue(instruction.reg3))
+	result := ALU.Adder(getRegisterValue(instruction.reg2), -getRegisterValue(instruction.reg3))
 	setRegisterValue(instruction.reg1, result)
 
 	//set flag N
@@ -728,8 +626,8 @@ func (instruction *SubAndSetFlagsInstruction) execute() {
 		flagOverflow = false
 	}
 
-	//set flag C (unsigned addition overflow)
-	if uint64(getRegisterValue(instruction.reg2)) < uint64(getRegisterValue(instruction.reg3)) {
+	//set flag C (no borrow in unsigned subtraction)
+	if uint64(getRegisterValue(instruction.reg2)) >= uint64(getRegisterValue(instruction.reg3)) {
 		flagCarry = true
 	} else {
 		flagCarry = false
@@ -754,32 +652,22 @@ type AddImmediateAndSetFlagsInstruction struct {
 }
 
 func (instruction *AddImmediateAndSetFlagsInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^ADDIS X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, unsignedArithmeticImmediate, instruction.inst)
 }
 
 func (instruction *AddImmediateAndSetFlagsInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, unsignedArithmeticImmediate, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	constant, _ := strconv.Atoi(statement[indexHash+1:])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
 	instruction.constant = uint(constant)
 
 	return nil
@@ -840,32 +728,22 @@ type SubImmediateAndSetFlagsInstruction struct {
 }
 
 func (instruction *SubImmediateAndSetFlagsInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^SUBIS X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, unsignedArithmeticImmediate, instruction.inst)
 }
 
 func (instruction *SubImmediateAndSetFlagsInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, unsignedArithmeticImmediate, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	constant, _ := strconv.Atoi(statement[indexHash+1:])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
 	instruction.constant = uint(constant)
 
 	return nil
@@ -899,8 +777,8 @@ func (instruction *SubImmediateAndSetFlagsInstruction) execute() {
 		flagOverflow = false
 	}
 
-	//set flag C (unsigned addition overflow)
-	if uint64(getRegisterValue(instruction.reg2)) < uint64(instruction.constant) {
+	//set flag C (no borrow in unsigned subtraction)
+	if uint64(getRegisterValue(instruction.reg2)) >= uint64(instruction.constant) {
 		flagCarry = true
 	} else {
 		flagCarry = false
@@ -921,53 +799,44 @@ type LoadInstruction struct {
 	inst   string
 	reg1   uint
 	reg2   uint
-	offset uint
+	offset int64
 }
 
 func (instruction *LoadInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^LDUR (X([0-9]|1[0-9]|2[0-7])|LR), \\[(X([0-9]|1[0-9]|2[0-7])|SP), #(0|[1-9][0-9]*)\\]$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.ADDRESS_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[1].Expression, addressOffset, instruction.inst)
 }
 
 func (instruction *LoadInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexLR, indexComma, indexHash, indexBracket, offset int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexLR = strings.Index(statement, "LR")
-		indexComma = strings.Index(statement, ",")
-		if indexX != -1 {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		} else if indexLR != -1 {
-			registers[i] = LR
-		} else {
-			registers[i] = SP
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[1].Expression, addressOffset, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	indexBracket = strings.Index(statement, "]")
-	offset, _ = strconv.Atoi(statement[indexHash+1 : indexBracket])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.offset = uint(offset)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.offset = offset
 
 	//check for alignment restriction
-	if (getRegisterValue(instruction.reg2)+int64(instruction.offset))%4 != 0 {
+	if (getRegisterValue(instruction.reg2)+instruction.offset)%4 != 0 {
 		return errors.New("Alignment restriction violation in : " + instruction.inst)
 	}
 
+	address := getRegisterValue(instruction.reg2) + instruction.offset
+	if !isValidAddress(address, 4) {
+		return errors.New("Address out of range in : " + instruction.inst)
+	}
+
 	return nil
 }
 
 func (instruction *LoadInstruction) execute() {
-	memoryIndex := ALU.Adder(getRegisterValue(instruction.reg2), int64(instruction.offset)) / 4
-	memoryValue := dataMemory.read(uint64(memoryIndex))
+	address := ALU.Adder(getRegisterValue(instruction.reg2), instruction.offset)
+	memoryValue := int32(dataMemory.load(address, 4))
 	setRegisterValue(instruction.reg1, int64(memoryValue))
 	InstructionMem.updatePC()
 }
@@ -984,54 +853,45 @@ type StoreInstruction struct {
 	inst   string
 	reg1   uint
 	reg2   uint
-	offset uint
+	offset int64
 }
 
 func (instruction *StoreInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^STUR (X([0-9]|1[0-9]|2[0-7])|LR), \\[(X([0-9]|1[0-9]|2[0-7])|SP), #(0|[1-9][0-9]*)\\]$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.ADDRESS_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[1].Expression, addressOffset, instruction.inst)
 }
 
 func (instruction *StoreInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexLR, indexComma, indexHash, indexBracket, offset int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexLR = strings.Index(statement, "LR")
-		indexComma = strings.Index(statement, ",")
-		if indexX != -1 {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		} else if indexLR != -1 {
-			registers[i] = LR
-		} else {
-			registers[i] = SP
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[1].Expression, addressOffset, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	indexBracket = strings.Index(statement, "]")
-	offset, _ = strconv.Atoi(statement[indexHash+1 : indexBracket])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.offset = uint(offset)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.offset = offset
 
 	//check for alignment restriction
-	if (getRegisterValue(instruction.reg2)+int64(instruction.offset))%4 != 0 {
+	if (getRegisterValue(instruction.reg2)+instruction.offset)%4 != 0 {
 		return errors.New("Alignment restriction violation in : " + instruction.inst)
 	}
 
+	address := getRegisterValue(instruction.reg2) + instruction.offset
+	if !isValidAddress(address, 4) {
+		return errors.New("Address out of range in : " + instruction.inst)
+	}
+
 	return nil
 }
 
 func (instruction *StoreInstruction) execute() {
-	memoryIndex := ALU.Adder(getRegisterValue(instruction.reg2), int64(instruction.offset)) / 4
+	address := ALU.Adder(getRegisterValue(instruction.reg2), instruction.offset)
 	registerValue := getRegisterValue(instruction.reg1)
-	dataMemory.write(uint64(memoryIndex), int32(registerValue))
+	dataMemory.store(address, 4, registerValue)
 	InstructionMem.updatePC()
 }
 
@@ -1047,54 +907,44 @@ type LoadHalfInstruction struct {
 	inst   string
 	reg1   uint
 	reg2   uint
-	offset uint
+	offset int64
 }
 
 func (instruction *LoadHalfInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^LDURH X([0-9]|1[0-9]|2[0-7]), \\[X([0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)\\]$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.ADDRESS_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[1].Expression, addressOffset, instruction.inst)
 }
 
 func (instruction *LoadHalfInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash, indexBracket, offset int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		statement = statement[indexComma+1:]
-	}
-	indexHash = strings.Index(statement, "#")
-	indexBracket = strings.Index(statement, "]")
-	offset, _ = strconv.Atoi(statement[indexHash+1 : indexBracket])
-
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.offset = uint(offset)
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[1].Expression, addressOffset, instruction.inst)
+	if err != nil {
+		return err
+	}
+
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.offset = offset
 
 	//check for alignment restriction
-	if (getRegisterValue(instruction.reg2)+int64(instruction.offset))%4 != 0 && (getRegisterValue(instruction.reg2)+int64(instruction.offset))%4 != 2 {
+	if (getRegisterValue(instruction.reg2)+instruction.offset)%4 != 0 && (getRegisterValue(instruction.reg2)+instruction.offset)%4 != 2 {
 		return errors.New("Alignment restriction violation in : " + instruction.inst)
 	}
 
+	address := getRegisterValue(instruction.reg2) + instruction.offset
+	if !isValidAddress(address, 2) {
+		return errors.New("Address out of range in : " + instruction.inst)
+	}
+
 	return nil
 }
 
 func (instruction *LoadHalfInstruction) execute() {
-	var memoryValue int16
-	var shift uint = 16
-	memoryIndex := ALU.Adder(getRegisterValue(instruction.reg2), int64(instruction.offset))
-	if memoryIndex%4 == 0 {
-		// extract upper 16 bits
-		memoryValue = int16(dataMemory.read(uint64(memoryIndex/4)) >> shift)
-	} else {
-		// extract lower 16 bits
-		memoryValue = int16(dataMemory.read(uint64(memoryIndex / 4)))
-	}
+	address := ALU.Adder(getRegisterValue(instruction.reg2), instruction.offset)
+	memoryValue := int16(dataMemory.load(address, 2))
 	setRegisterValue(instruction.reg1, int64(memoryValue))
 
 	InstructionMem.updatePC()
@@ -1112,59 +962,45 @@ type StoreHalfInstruction struct {
 	inst   string
 	reg1   uint
 	reg2   uint
-	offset uint
+	offset int64
 }
 
 func (instruction *StoreHalfInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^STURH X([0-9]|1[0-9]|2[0-7]), \\[X([0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)\\]$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.ADDRESS_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[1].Expression, addressOffset, instruction.inst)
 }
 
 func (instruction *StoreHalfInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash, indexBracket, offset int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		statement = statement[indexComma+1:]
-	}
-	indexHash = strings.Index(statement, "#")
-	indexBracket = strings.Index(statement, "]")
-	offset, _ = strconv.Atoi(statement[indexHash+1 : indexBracket])
-
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.offset = uint(offset)
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[1].Expression, addressOffset, instruction.inst)
+	if err != nil {
+		return err
+	}
+
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.offset = offset
 
 	//check for alignment restriction
-	if (getRegisterValue(instruction.reg2)+int64(instruction.offset))%4 != 0 && (getRegisterValue(instruction.reg2)+int64(instruction.offset))%4 != 2 {
+	if (getRegisterValue(instruction.reg2)+instruction.offset)%4 != 0 && (getRegisterValue(instruction.reg2)+instruction.offset)%4 != 2 {
 		return errors.New("Alignment restriction violation in : " + instruction.inst)
 	}
 
+	address := getRegisterValue(instruction.reg2) + instruction.offset
+	if !isValidAddress(address, 2) {
+		return errors.New("Address out of range in : " + instruction.inst)
+	}
+
 	return nil
 }
 
 func (instruction *StoreHalfInstruction) execute() {
-	var registerValue int16
-	var shift uint = 16
-	registerValue = int16(getRegisterValue(instruction.reg1))
-	memoryIndex := ALU.Adder(getRegisterValue(instruction.reg2), int64(instruction.offset))
-	currentMemoryValue := dataMemory.read(uint64(memoryIndex / 4))
-	if memoryIndex%4 == 0 {
-		// store in upper 16 bits
-		currentMemoryValue = currentMemoryValue & ((1 << shift) - 1) // clear upper 16 bits
-		currentMemoryValue = currentMemoryValue | (int32(registerValue) << shift)
-	} else {
-		// store in lower 16 bits
-		currentMemoryValue = currentMemoryValue & -(1 << shift) // clear lower 16 bits
-		currentMemoryValue = currentMemoryValue | int32(registerValue)
-	}
-	dataMemory.write(uint64(memoryIndex/4), currentMemoryValue)
+	registerValue := getRegisterValue(instruction.reg1)
+	address := ALU.Adder(getRegisterValue(instruction.reg2), instruction.offset)
+	dataMemory.store(address, 2, registerValue)
 
 	InstructionMem.updatePC()
 }
@@ -1181,56 +1017,40 @@ type LoadByteInstruction struct {
 	inst   string
 	reg1   uint
 	reg2   uint
-	offset uint
+	offset int64
 }
 
 func (instruction *LoadByteInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^LDURB X([0-9]|1[0-9]|2[0-7]), \\[X([0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)\\]$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.ADDRESS_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[1].Expression, addressOffset, instruction.inst)
 }
 
 func (instruction *LoadByteInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash, indexBracket, offset int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		statement = statement[indexComma+1:]
-	}
-	indexHash = strings.Index(statement, "#")
-	indexBracket = strings.Index(statement, "]")
-	offset, _ = strconv.Atoi(statement[indexHash+1 : indexBracket])
-
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.offset = uint(offset)
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[1].Expression, addressOffset, instruction.inst)
+	if err != nil {
+		return err
+	}
+
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.offset = offset
+
+	address := getRegisterValue(instruction.reg2) + instruction.offset
+	if !isValidAddress(address, 1) {
+		return errors.New("Address out of range in : " + instruction.inst)
+	}
 
 	return nil
 }
 
 func (instruction *LoadByteInstruction) execute() {
-	var registerValue int8
-	memoryIndex := ALU.Adder(getRegisterValue(instruction.reg2), int64(instruction.offset))
-	memoryValue := dataMemory.read(uint64(memoryIndex / 4))
-	if memoryIndex%4 == 0 {
-		// extract bits[31:24]
-		registerValue = int8(memoryValue >> 24)
-	} else if memoryIndex%4 == 1 {
-		// extract bits[23:16]
-		registerValue = int8(memoryValue >> 16)
-	} else if memoryIndex%4 == 2 {
-		// extract bits[15:8]
-		registerValue = int8(memoryValue >> 8)
-	} else {
-		// extract bit[7:0]
-		registerValue = int8(memoryValue)
-	}
-	setRegisterValue(instruction.reg1, int64(registerValue))
+	address := ALU.Adder(getRegisterValue(instruction.reg2), instruction.offset)
+	memoryValue := int8(dataMemory.load(address, 1))
+	setRegisterValue(instruction.reg1, int64(memoryValue))
 
 	InstructionMem.updatePC()
 }
@@ -1247,69 +1067,40 @@ type StoreByteInstruction struct {
 	inst   string
 	reg1   uint
 	reg2   uint
-	offset uint
+	offset int64
 }
 
 func (instruction *StoreByteInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^STURB X([0-9]|1[0-9]|2[0-7]), \\[X([0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)\\]$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.ADDRESS_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[1].Expression, addressOffset, instruction.inst)
 }
 
 func (instruction *StoreByteInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash, indexBracket, offset int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		statement = statement[indexComma+1:]
-	}
-	indexHash = strings.Index(statement, "#")
-	indexBracket = strings.Index(statement, "]")
-	offset, _ = strconv.Atoi(statement[indexHash+1 : indexBracket])
-
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.offset = uint(offset)
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[1].Expression, addressOffset, instruction.inst)
+	if err != nil {
+		return err
+	}
+
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.offset = offset
+
+	address := getRegisterValue(instruction.reg2) + instruction.offset
+	if !isValidAddress(address, 1) {
+		return errors.New("Address out of range in : " + instruction.inst)
+	}
 
 	return nil
 }
 
 func (instruction *StoreByteInstruction) execute() {
-	var registerValue int8
-	registerValue = int8(getRegisterValue(instruction.reg1))
-	memoryIndex := ALU.Adder(getRegisterValue(instruction.reg2), int64(instruction.offset))
-	currentMemoryValue := dataMemory.read(uint64(memoryIndex / 4))
-	if memoryIndex%4 == 0 {
-
-		// store in bits[31:24]
-		currentMemoryValue = currentMemoryValue & ((1 << 24) - 1) // clear bits[31:24]
-		currentMemoryValue = currentMemoryValue | (int32(registerValue) << 24)
-
-	} else if memoryIndex%4 == 1 {
-
-		// store in bits[23:16]
-		currentMemoryValue = currentMemoryValue & (((1 << 16) - 1) | -(1 << 24)) // clear bits[23:16]
-		currentMemoryValue = currentMemoryValue | (int32(registerValue) << 16)
-
-	} else if memoryIndex%4 == 2 {
-
-		// store in bits[15:8]
-		currentMemoryValue = currentMemoryValue & (((1 << 8) - 1) | -(1 << 16)) // clear bits[15:8]
-		currentMemoryValue = currentMemoryValue | (int32(registerValue) << 8)
-
-	} else {
-
-		// store in bits[7:0]
-		currentMemoryValue = currentMemoryValue & -(1 << 8) // clear bits[7:0]
-		currentMemoryValue = currentMemoryValue | int32(registerValue)
-
-	}
-	dataMemory.write(uint64(memoryIndex/4), currentMemoryValue)
+	registerValue := getRegisterValue(instruction.reg1)
+	address := ALU.Adder(getRegisterValue(instruction.reg2), instruction.offset)
+	dataMemory.store(address, 1, registerValue)
 
 	InstructionMem.updatePC()
 }
@@ -1393,28 +1184,31 @@ type MoveWithZeroInstruction struct {
 }
 
 func (instruction *MoveWithZeroInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^MOVZ X([0-9]|1[0-9]|2[0-7]), (0|[1-9][0-9]*), LSL (0|1|2|3)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND, Assembler.SHIFT_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	operands := getOperands(instruction.inst)
+	err = checkImmediate(operands[1].Expression, moveImmediate, instruction.inst)
+	if err != nil || Assembler.UsesSymbols(operands[2].Expression) {
+		return err
+	}
+	_, err = parseMoveShift(operands[2].Expression, instruction.inst)
+	return err
 }
 
 func (instruction *MoveWithZeroInstruction) parse() error {
-	statement := instruction.inst
-	var indexX, indexComma int
-
-	indexX = strings.Index(statement, "X")
-	indexComma = strings.Index(statement, ",")
-	register, _ := strconv.Atoi(statement[indexX+1 : indexComma])
-
-	statement = strings.TrimSpace(statement[indexComma+1:])
-	indexComma = strings.Index(statement, ",")
-	constant, _ := strconv.Atoi(statement[:indexComma])
-
-	offset := uint(statement[len(statement)-1] - '0')
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[1].Expression, moveImmediate, instruction.inst)
+	if err != nil {
+		return err
+	}
+	offset, err := parseMoveShift(operands[2].Expression, instruction.inst)
+	if err != nil {
+		return err
+	}
 
-	instruction.reg1 = uint(register)
+	instruction.reg1 = operands[0].Register
 	instruction.constant = uint16(constant)
 	instruction.offset = offset
 
@@ -1445,28 +1239,31 @@ type MoveWithKeepInstruction struct {
 }
 
 func (instruction *MoveWithKeepInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^MOVK X([0-9]|1[0-9]|2[0-7]), (0|[1-9][0-9]*), LSL (0|1|2|3)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND, Assembler.SHIFT_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	operands := getOperands(instruction.inst)
+	err = checkImmediate(operands[1].Expression, moveImmediate, instruction.inst)
+	if err != nil || Assembler.UsesSymbols(operands[2].Expression) {
+		return err
+	}
+	_, err = parseMoveShift(operands[2].Expression, instruction.inst)
+	return err
 }
 
 func (instruction *MoveWithKeepInstruction) parse() error {
-	statement := instruction.inst
-	var indexX, indexComma int
-
-	indexX = strings.Index(statement, "X")
-	indexComma = strings.Index(statement, ",")
-	register, _ := strconv.Atoi(statement[indexX+1 : indexComma])
-
-	statement = strings.TrimSpace(statement[indexComma+1:])
-	indexComma = strings.Index(statement, ",")
-	constant, _ := strconv.Atoi(statement[:indexComma])
-
-	offset := uint(statement[len(statement)-1] - '0')
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[1].Expression, moveImmediate, instruction.inst)
+	if err != nil {
+		return err
+	}
+	offset, err := parseMoveShift(operands[2].Expression, instruction.inst)
+	if err != nil {
+		return err
+	}
 
-	instruction.reg1 = uint(register)
+	instruction.reg1 = operands[0].Register
 	instruction.constant = uint16(constant)
 	instruction.offset = offset
 
@@ -1510,35 +1307,14 @@ type AndInstruction struct {
 }
 
 func (instruction *AndInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^AND X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *AndInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
@@ -1565,35 +1341,14 @@ type OrInstruction struct {
 }
 
 func (instruction *OrInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^ORR X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *OrInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
@@ -1620,35 +1375,14 @@ type ExclusiveOrInstruction struct {
 }
 
 func (instruction *ExclusiveOrInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^EOR X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7])$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *ExclusiveOrInstruction) parse() error {
-	statement := instruction.inst
-	var registers [3]int
-	var i, indexX, indexComma int
-	for i = 0; i < 3; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if indexComma == -1 {
-			indexComma = len(statement)
-		}
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		if indexComma < len(statement) {
-			statement = statement[indexComma+1:]
-		}
-	}
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
-	instruction.reg3 = uint(registers[2])
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
 
 	return nil
 }
@@ -1659,6 +1393,41 @@ func (instruction *ExclusiveOrInstruction) execute() {
 	InstructionMem.updatePC()
 }
 
+/*
+INSTRUCTION : LOGICAL AND AND SET FLAGS
+
+	Example : ANDS X1, X2, X3
+	Meaning : X1 = X2 & X3
+
+Comments : Bitwise-And of X2 and X3, stores result in X1 and sets condition codes
+*/
+type AndAndSetFlagsInstruction struct {
+	inst string
+	reg1 uint
+	reg2 uint
+	reg3 uint
+}
+
+func (instruction *AndAndSetFlagsInstruction) checkSyntax() error {
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND)
+}
+
+func (instruction *AndAndSetFlagsInstruction) parse() error {
+	operands := getOperands(instruction.inst)
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.reg3 = operands[2].Register
+
+	return nil
+}
+
+func (instruction *AndAndSetFlagsInstruction) execute() {
+	result := ALU.LogicalAND(getRegisterValue(instruction.reg2), getRegisterValue(instruction.reg3))
+	setRegisterValue(instruction.reg1, result)
+	setLogicalFlags(result)
+	InstructionMem.updatePC()
+}
+
 /*
 INSTRUCTION : LOGICAL AND IMMEDIATE
 
@@ -1675,32 +1444,22 @@ type AndImmediateInstruction struct {
 }
 
 func (instruction *AndImmediateInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^ANDI X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, logicalImmediate, instruction.inst)
 }
 
 func (instruction *AndImmediateInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, logicalImmediate, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	constant, _ := strconv.Atoi(statement[indexHash+1:])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
 	instruction.constant = uint(constant)
 
 	return nil
@@ -1728,32 +1487,22 @@ type OrImmediateInstruction struct {
 }
 
 func (instruction *OrImmediateInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^ORRI X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, logicalImmediate, instruction.inst)
 }
 
 func (instruction *OrImmediateInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, logicalImmediate, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	constant, _ := strconv.Atoi(statement[indexHash+1:])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
 	instruction.constant = uint(constant)
 
 	return nil
@@ -1781,32 +1530,22 @@ type ExclusiveOrImmediateInstruction struct {
 }
 
 func (instruction *ExclusiveOrImmediateInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^EORI X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), #(0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, logicalImmediate, instruction.inst)
 }
 
 func (instruction *ExclusiveOrImmediateInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, logicalImmediate, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	constant, _ := strconv.Atoi(statement[indexHash+1:])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
 	instruction.constant = uint(constant)
 
 	return nil
@@ -1818,6 +1557,50 @@ func (instruction *ExclusiveOrImmediateInstruction) execute() {
 	InstructionMem.updatePC()
 }
 
+/*
+INSTRUCTION : LOGICAL AND IMMEDIATE AND SET FLAGS
+
+	Example : ANDIS X1, X2, #20
+	Meaning : X1 = X2 & 20
+
+Comments : Bitwise-And of X2 with a constant, stores result in X1 and sets condition codes
+*/
+type AndImmediateAndSetFlagsInstruction struct {
+	inst     string
+	reg1     uint
+	reg2     uint
+	constant uint
+}
+
+func (instruction *AndImmediateAndSetFlagsInstruction) checkSyntax() error {
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
+	}
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, logicalImmediate, instruction.inst)
+}
+
+func (instruction *AndImmediateAndSetFlagsInstruction) parse() error {
+	operands := getOperands(instruction.inst)
+	constant, err := parseImmediate(operands[2].Expression, logicalImmediate, instruction.inst)
+	if err != nil {
+		return err
+	}
+
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
+	instruction.constant = uint(constant)
+
+	return nil
+}
+
+func (instruction *AndImmediateAndSetFlagsInstruction) execute() {
+	result := ALU.LogicalAND(getRegisterValue(instruction.reg2), int64(instruction.constant))
+	setRegisterValue(instruction.reg1, result)
+	setLogicalFlags(result)
+	InstructionMem.updatePC()
+}
+
 /*
 INSTRUCTION : LOGICAL LEFT SHIFT
 
@@ -1834,32 +1617,22 @@ type LeftShiftInstruction struct {
 }
 
 func (instruction *LeftShiftInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^LSL X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), (0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, shiftAmount, instruction.inst)
 }
 
 func (instruction *LeftShiftInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[2].Expression, shiftAmount, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	offset, _ := strconv.Atoi(statement[indexHash+1:])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
 	instruction.offset = uint(offset)
 
 	return nil
@@ -1887,32 +1660,22 @@ type RightShiftInstruction struct {
 }
 
 func (instruction *RightShiftInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^LSR X([0-9]|1[0-9]|2[0-7]), X(ZR|[0-9]|1[0-9]|2[0-7]), (0|[1-9][0-9]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+	err := checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.REGISTER_OPERAND, Assembler.IMMEDIATE_OPERAND)
+	if err != nil {
+		return err
 	}
-	return nil
+	return checkImmediate(getOperands(instruction.inst)[2].Expression, shiftAmount, instruction.inst)
 }
 
 func (instruction *RightShiftInstruction) parse() error {
-	statement := instruction.inst
-	var registers [2]int
-	var i, indexX, indexComma, indexHash int
-	for i = 0; i < 2; i++ {
-		indexX = strings.Index(statement, "X")
-		indexComma = strings.Index(statement, ",")
-		if statement[indexX+1:indexComma] == "ZR" {
-			registers[i] = 31
-		} else {
-			registers[i], _ = strconv.Atoi(statement[indexX+1 : indexComma])
-		}
-		statement = statement[indexComma+1:]
+	operands := getOperands(instruction.inst)
+	offset, err := parseImmediate(operands[2].Expression, shiftAmount, instruction.inst)
+	if err != nil {
+		return err
 	}
-	indexHash = strings.Index(statement, "#")
-	offset, _ := strconv.Atoi(statement[indexHash+1:])
 
-	instruction.reg1 = uint(registers[0])
-	instruction.reg2 = uint(registers[1])
+	instruction.reg1 = operands[0].Register
+	instruction.reg2 = operands[1].Register
 	instruction.offset = uint(offset)
 
 	return nil
@@ -1939,36 +1702,26 @@ type BranchOnZeroInstruction struct {
 }
 
 func (instruction *BranchOnZeroInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^CBZ X([0-9]|1[0-9]|2[0-7]), ([a-zA-Z][[:alnum:]]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.LABEL_OPERAND)
 }
 
 func (instruction *BranchOnZeroInstruction) parse() error {
-	statement := instruction.inst
-	var indexX, indexComma int
-
-	indexX = strings.Index(statement, "X")
-	indexComma = strings.Index(statement, ",")
-
-	register, _ := strconv.Atoi(statement[indexX+1 : indexComma])
-	labelName := strings.TrimSpace(statement[indexComma+1:])
-	labelPC, isValidLabel := InstructionMem.Labels[labelName]
-
-	if !isValidLabel {
-		return errors.New("Invalid label name " + labelName + " in " + instruction.inst)
+	operands := getOperands(instruction.inst)
+	labelPC, err := getLabelPC(operands[1].Expression, instruction.inst)
+	if err != nil {
+		return err
 	}
 
-	instruction.reg1 = uint(register)
+	instruction.reg1 = operands[0].Register
 	instruction.offset = labelPC - InstructionMem.PC
 
 	return nil
 }
 
 func (instruction *BranchOnZeroInstruction) execute() {
-	if getRegisterValue(instruction.reg1) == 0 {
+	isBranching := getRegisterValue(instruction.reg1) == 0
+	traceBranch(isBranching, InstructionMem.PC+instruction.offset)
+	if isBranching {
 		InstructionMem.updatePC(instruction.offset)
 	} else {
 		InstructionMem.updatePC()
@@ -1990,36 +1743,26 @@ type BranchOnNonZeroInstruction struct {
 }
 
 func (instruction *BranchOnNonZeroInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^CBNZ X([0-9]|1[0-9]|2[0-7]), ([a-zA-Z][[:alnum:]]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND, Assembler.LABEL_OPERAND)
 }
 
 func (instruction *BranchOnNonZeroInstruction) parse() error {
-	statement := instruction.inst
-	var indexX, indexComma int
-
-	indexX = strings.Index(statement, "X")
-	indexComma = strings.Index(statement, ",")
-
-	register, _ := strconv.Atoi(statement[indexX+1 : indexComma])
-	labelName := strings.TrimSpace(statement[indexComma+1:])
-	labelPC, isValidLabel := InstructionMem.Labels[labelName]
-
-	if !isValidLabel {
-		return errors.New("Invalid label name " + labelName + " in " + instruction.inst)
+	operands := getOperands(instruction.inst)
+	labelPC, err := getLabelPC(operands[1].Expression, instruction.inst)
+	if err != nil {
+		return err
 	}
 
-	instruction.reg1 = uint(register)
+	instruction.reg1 = operands[0].Register
 	instruction.offset = labelPC - InstructionMem.PC
 
 	return nil
 }
 
 func (instruction *BranchOnNonZeroInstruction) execute() {
-	if getRegisterValue(instruction.reg1) != 0 {
+	isBranching := getRegisterValue(instruction.reg1) != 0
+	traceBranch(isBranching, InstructionMem.PC+instruction.offset)
+	if isBranching {
 		InstructionMem.updatePC(instruction.offset)
 	} else {
 		InstructionMem.updatePC()
@@ -2041,25 +1784,21 @@ type ConditionalBranchInstruction struct {
 }
 
 func (instruction *ConditionalBranchInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^B\\.(EQ|NE|LT|LE|GT|GE|LO|LS|HI|HS) ([a-zA-Z][[:alnum:]]*)$")
+	r, _ := regexp.Compile("^B\\.(EQ|NE|LT|LE|GT|GE|LO|LS|HI|HS) ")
 	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
+		return errors.New("Invalid condition code in " + instruction.inst)
 	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.LABEL_OPERAND)
 }
 
 func (instruction *ConditionalBranchInstruction) parse() error {
-	statement := instruction.inst
-
-	conditionCode := statement[2:4]
-	labelName := strings.TrimSpace(statement[5:])
-	labelPC, isValidLabel := InstructionMem.Labels[labelName]
-
-	if !isValidLabel {
-		return errors.New("Invalid label name " + labelName + " in " + instruction.inst)
+	operands := getOperands(instruction.inst)
+	labelPC, err := getLabelPC(operands[0].Expression, instruction.inst)
+	if err != nil {
+		return err
 	}
 
-	instruction.condition = conditionCode
+	instruction.condition = instruction.inst[2:4]
 	instruction.offset = labelPC - InstructionMem.PC
 
 	return nil
@@ -2103,6 +1842,7 @@ func (instruction *ConditionalBranchInstruction) execute() {
 
 	}
 
+	traceBranch(is_branching, InstructionMem.PC+instruction.offset)
 	if is_branching {
 		InstructionMem.updatePC(instruction.offset)
 	} else {
@@ -2124,21 +1864,14 @@ type BranchInstruction struct {
 }
 
 func (instruction *BranchInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^B ([a-zA-Z][[:alnum:]]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.LABEL_OPERAND)
 }
 
 func (instruction *BranchInstruction) parse() error {
-	statement := instruction.inst
-
-	labelName := strings.TrimSpace(statement[2:])
-	labelPC, isValidLabel := InstructionMem.Labels[labelName]
-
-	if !isValidLabel {
-		return errors.New("Invalid label name " + labelName + " in " + instruction.inst)
+	operands := getOperands(instruction.inst)
+	labelPC, err := getLabelPC(operands[0].Expression, instruction.inst)
+	if err != nil {
+		return err
 	}
 
 	instruction.offset = labelPC - InstructionMem.PC
@@ -2165,27 +1898,15 @@ type BranchToRegisterInstruction struct {
 }
 
 func (instruction *BranchToRegisterInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^BR (X([0-9]|1[0-9]|2[0-7])|LR)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.REGISTER_OPERAND)
 }
 
 func (instruction *BranchToRegisterInstruction) parse() error {
-	statement := instruction.inst
-	registerName := strings.TrimSpace(statement[3:])
-	var register uint
-
-	if strings.Compare(registerName, "LR") == 0 {
-		register = 30
-	} else {
-		registerValue, _ := strconv.Atoi(statement[4:])
-		register = uint(registerValue)
-	}
+	operands := getOperands(instruction.inst)
+	register := operands[0].Register
 
 	if !IsValidPC(getRegisterValue(register)) {
-		return errors.New("Invalid address in register " + registerName + " in " + instruction.inst)
+		return errors.New("Invalid address in register " + operands[0].Text + " in " + instruction.inst)
 	}
 
 	instruction.reg1 = register
@@ -2195,6 +1916,7 @@ func (instruction *BranchToRegisterInstruction) parse() error {
 }
 
 func (instruction *BranchToRegisterInstruction) execute() {
+	popFrames(InstructionMem.PC + instruction.offset)
 	InstructionMem.updatePC(instruction.offset)
 }
 
@@ -2208,27 +1930,22 @@ Comments : For procedure call (PC-relative)
 */
 type BranchWithLinkInstruction struct {
 	inst   string
+	label  string
 	offset int64
 }
 
 func (instruction *BranchWithLinkInstruction) checkSyntax() error {
-	r, _ := regexp.Compile("^BL ([a-zA-Z][[:alnum:]]*)$")
-	if r.MatchString(instruction.inst) == false {
-		return errors.New("Syntax error occurred in " + instruction.inst)
-	}
-	return nil
+	return checkOperands(instruction.inst, Assembler.LABEL_OPERAND)
 }
 
 func (instruction *BranchWithLinkInstruction) parse() error {
-	statement := instruction.inst
-
-	labelName := strings.TrimSpace(statement[3:])
-	labelPC, isValidLabel := InstructionMem.Labels[labelName]
-
-	if !isValidLabel {
-		return errors.New("Invalid label name " + labelName + " in " + instruction.inst)
+	operands := getOperands(instruction.inst)
+	labelPC, err := getLabelPC(operands[0].Expression, instruction.inst)
+	if err != nil {
+		return err
 	}
 
+	instruction.label = operands[0].Expression
 	instruction.offset = labelPC - InstructionMem.PC
 
 	return nil
@@ -2236,5 +1953,6 @@ func (instruction *BranchWithLinkInstruction) parse() error {
 
 func (instruction *BranchWithLinkInstruction) execute() {
 	setRegisterValue(30, InstructionMem.PC+INCREMENT)
+	pushFrame(instruction.label, InstructionMem.PC)
 	InstructionMem.updatePC(instruction.offset)
 }
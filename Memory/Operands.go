@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"errors"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+)
+
+// Function to check that an instruction has exactly the given kinds of operands.
+// Mnemonics and registers may be written in any case and with any spacing.
+func checkOperands(inst string, kinds ...string) error {
+	instruction, err := Assembler.ParseInstruction(inst)
+	if err != nil {
+		return err
+	}
+	return instruction.CheckOperands(kinds...)
+}
+
+// Function to return the operands of an instruction that passed checkOperands.
+func getOperands(inst string) []Assembler.Operand {
+	instruction, _ := Assembler.ParseInstruction(inst)
+	return instruction.Operands
+}
+
+// Function to find the instruction a label points to.
+func getLabelPC(labelName string, inst string) (int64, error) {
+	labelPC, isValidLabel := InstructionMem.Labels[labelName]
+	if !isValidLabel {
+		return 0, errors.New("Invalid label name " + labelName + " in " + inst)
+	}
+	return labelPC, nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+// ReturnAddresses follows the registers and words of data memory that hold a return address.
+// Return addresses are instruction numbers in ARMed and byte addresses in hardware, so a value written
+// by BL, and its copies made by moves, stores and loads, stand for 4 times their value in hardware.
+type ReturnAddresses struct {
+	registers [32]bool
+	words     map[int64]bool
+}
+
+// NewReturnAddresses is a function to create a tracker in which nothing holds a return address.
+func NewReturnAddresses() *ReturnAddresses {
+	return &ReturnAddresses{words: make(map[int64]bool)}
+}
+
+// Observe is a method to update which registers and words hold a return address after an instruction.
+// A write holds one if it is made by BL, or if it copies the unchanged value of a register or word that holds one.
+func (addresses *ReturnAddresses) Observe(execution *Execution) {
+	for _, write := range execution.RegistersWritten {
+		isAddress := execution.Mnemonic == "BL"
+		for _, register := range execution.RegistersRead {
+			value := GetRegisterValue(register)
+			if register == write.Register {
+				value = write.OldValue
+			}
+			isAddress = isAddress || (addresses.registers[register] && value == write.NewValue)
+		}
+		for _, access := range execution.MemoryAccesses {
+			isAddress = isAddress || (!access.IsWrite && access.Size == WORD_SIZE && addresses.words[access.Address])
+		}
+		addresses.registers[write.Register] = isAddress
+	}
+
+	for _, access := range execution.MemoryAccesses {
+		if !access.IsWrite {
+			continue
+		}
+		isAddress := false
+		for _, register := range execution.RegistersRead {
+			value := GetRegisterValue(register)
+			isAddress = isAddress || (addresses.registers[register] && access.Size == WORD_SIZE && uint32(value) == uint32(access.Value))
+		}
+		addresses.words[access.Address] = isAddress
+	}
+}
+
+// RegisterInHardware is a method to find the value a register holds in hardware.
+func (addresses *ReturnAddresses) RegisterInHardware(register uint, value uint64) uint64 {
+	if addresses.registers[register] {
+		return value * WORD_SIZE
+	}
+	return value
+}
+
+// WordInHardware is a method to find the value the word at a byte address holds in hardware.
+func (addresses *ReturnAddresses) WordInHardware(address int64, value uint64) uint64 {
+	if addresses.words[address] {
+		return value * WORD_SIZE
+	}
+	return value
+}
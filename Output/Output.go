@@ -0,0 +1,150 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reasons for a snapshot. A step snapshot is taken while the program is still running.
+const (
+	STEP_REASON  = "step"
+	END_REASON   = "end"
+	ERROR_REASON = "error"
+	LIMIT_REASON = "limit"
+)
+
+// Registers holds the value of every register, written out by name.
+type Registers [32]int64
+
+// Flags holds the condition flags.
+type Flags struct {
+	N bool `json:"N"`
+	Z bool `json:"Z"`
+	V bool `json:"V"`
+	C bool `json:"C"`
+}
+
+// Region is a run of consecutive non-zero words of data memory, starting at a byte address.
+type Region struct {
+	Address int64   `json:"address"`
+	Words   []int32 `json:"words"`
+}
+
+// Snapshot is the state of the machine in a form that scripts can read.
+type Snapshot struct {
+	PC           int64     `json:"pc"`
+	Reason       string    `json:"exit_reason"`
+	Error        string    `json:"error,omitempty"`
+	Instructions int64     `json:"instructions"`
+	Registers    Registers `json:"registers"`
+	Flags        Flags     `json:"flags"`
+	Memory       []Region  `json:"memory"`
+}
+
+// MarshalJSON is a method to write the registers as an object, in order of register number.
+func (registers Registers) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i, value := range registers {
+		if i != 0 {
+			buffer.WriteString(",")
+		}
+		fmt.Fprintf(&buffer, "%q:%d", Memory.RegisterName(i), value)
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// Capture is a function to take a snapshot of the machine.
+// The message explains an error or a limit.
+func Capture(reason string, message string) *Snapshot {
+	flags := Memory.GetFlags()
+	snapshot := Snapshot{
+		PC:           Memory.InstructionMem.PC,
+		Reason:       reason,
+		Error:        message,
+		Instructions: Memory.ExecutedInstructions,
+		Flags:        Flags{N: flags[0], Z: flags[1], V: flags[2], C: flags[3]},
+		Memory:       []Region{},
+	}
+	for i := range snapshot.Registers {
+		snapshot.Registers[i] = Memory.GetRegisterValue(uint(i))
+	}
+
+	var region *Region
+	for index := int64(0); index < Memory.MEMORY_SIZE; index++ {
+		word := Memory.GetMemoryWord(index)
+		if word == 0 {
+			region = nil
+			continue
+		}
+		if region == nil {
+			snapshot.Memory = append(snapshot.Memory, Region{Address: index * Memory.WORD_SIZE})
+			region = &snapshot.Memory[len(snapshot.Memory)-1]
+		}
+		region.Words = append(region.Words, word)
+	}
+	return &snapshot
+}
+
+// Writer writes snapshots as JSON, one object per line, or as YAML documents.
+type Writer struct {
+	output io.Writer
+	format string
+}
+
+// NewWriter is a function to create a writer for the format json or yaml.
+func NewWriter(output io.Writer, format string) (*Writer, error) {
+	format = strings.ToLower(format)
+	if format != "json" && format != "yaml" {
+		return nil, errors.New("Error : Unknown output format " + format + ", expected json or yaml")
+	}
+	return &Writer{output, format}, nil
+}
+
+// Write is a method to write a snapshot.
+func (writer *Writer) Write(snapshot *Snapshot) error {
+	if writer.format == "json" {
+		text, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(writer.output, "%s\n", text)
+		return err
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("---\n")
+	fmt.Fprintf(&buffer, "pc: %d\n", snapshot.PC)
+	fmt.Fprintf(&buffer, "exit_reason: %s\n", snapshot.Reason)
+	if snapshot.Error != "" {
+		fmt.Fprintf(&buffer, "error: %s\n", strconv.Quote(snapshot.Error))
+	}
+	fmt.Fprintf(&buffer, "instructions: %d\n", snapshot.Instructions)
+	buffer.WriteString("registers:\n")
+	for i, value := range snapshot.Registers {
+		fmt.Fprintf(&buffer, "  %s: %d\n", Memory.RegisterName(i), value)
+	}
+	// the flag names are quoted, as YAML 1.1 reads a bare N as false
+	fmt.Fprintf(&buffer, "flags:\n  \"N\": %t\n  \"Z\": %t\n  \"V\": %t\n  \"C\": %t\n", snapshot.Flags.N, snapshot.Flags.Z, snapshot.Flags.V, snapshot.Flags.C)
+	if len(snapshot.Memory) == 0 {
+		buffer.WriteString("memory: []\n")
+	} else {
+		buffer.WriteString("memory:\n")
+	}
+	for _, region := range snapshot.Memory {
+		words := make([]string, len(region.Words))
+		for i, word := range region.Words {
+			words[i] = strconv.FormatInt(int64(word), 10)
+		}
+		fmt.Fprintf(&buffer, "  - address: %d\n    words: [%s]\n", region.Address, strings.Join(words, ", "))
+	}
+	_, err := writer.output.Write(buffer.Bytes())
+	return err
+}
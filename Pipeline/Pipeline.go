@@ -0,0 +1,227 @@
+package pipeline
+
+import (
+	"fmt"
+	Memory "github.com/coderick14/ARMed/Memory"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Stages of the pipeline, in order
+const (
+	IF = iota
+	ID
+	EX
+	MEM
+	WB
+	STAGE_COUNT
+)
+
+// Names of the pipeline stages, as printed in the diagram
+var stageNames = [STAGE_COUNT]string{"IF", "ID", "EX", "MEM", "WB"}
+
+// FLAGS stands for the condition codes, which are tracked like an extra register
+const FLAGS = 32
+
+// DIAGRAM_ROWS is the number of instructions shown in the pipeline diagram
+const DIAGRAM_ROWS = 30
+
+// Struct to hold the stage cycles of an instruction in the pipeline
+type row struct {
+	instruction string
+	cycles      [STAGE_COUNT]int64
+	isFlushed   bool
+}
+
+// Struct to remember the last instruction that wrote a register
+type producer struct {
+	cycles [STAGE_COUNT]int64
+	isLoad bool
+}
+
+// Simulator is a timing model of the five-stage LEGv8 pipeline.
+// It follows the instructions executed by the emulator, so the architectural results
+// are the same as without it. Branches are predicted not taken and resolved in ID.
+type Simulator struct {
+	forwarding   bool
+	rows         []row
+	instructions int64
+	stalls       int64
+	flushes      int64
+	previous     [STAGE_COUNT]int64
+	nextFetch    int64
+	producers    map[uint]producer
+}
+
+// NewSimulator is a function to create a pipeline model, with or without forwarding.
+func NewSimulator(forwarding bool) *Simulator {
+	return &Simulator{forwarding: forwarding, nextFetch: 1, producers: make(map[uint]producer)}
+}
+
+// Observe is a method to place the next executed instruction in the pipeline.
+func (simulator *Simulator) Observe(execution *Memory.Execution) {
+	var cycles [STAGE_COUNT]int64
+	cycles[IF] = maxInt64(simulator.nextFetch, simulator.previous[IF]+1)
+	cycles[ID] = maxInt64(cycles[IF]+1, simulator.previous[ID]+1)
+
+	// data hazards hold the instruction in ID until its operands are available
+	ready := cycles[ID]
+	sources := execution.RegistersRead
+	if execution.ReadsFlags {
+		sources = append(sources[:len(sources):len(sources)], FLAGS)
+	}
+	for _, register := range sources {
+		source, isWritten := simulator.producers[register]
+		if register == Memory.XZR || !isWritten {
+			continue
+		}
+		ready = maxInt64(ready, simulator.operandReady(source, execution.IsBranch))
+	}
+	simulator.stalls += ready - cycles[ID]
+	cycles[ID] = ready
+	cycles[EX] = cycles[ID] + 1
+	cycles[MEM] = cycles[EX] + 1
+	cycles[WB] = cycles[MEM] + 1
+
+	isLoad := execution.Class() == Memory.LOAD_CLASS
+	for _, write := range execution.RegistersWritten {
+		simulator.producers[write.Register] = producer{cycles, isLoad}
+	}
+	if execution.SetsFlags {
+		simulator.producers[FLAGS] = producer{cycles, false}
+	}
+
+	simulator.instructions++
+	simulator.addRow(row{instruction: execution.Instruction, cycles: cycles})
+
+	// the instruction fetched behind a taken branch is flushed
+	simulator.nextFetch = cycles[IF] + 1
+	if execution.IsBranch && execution.IsTaken {
+		simulator.flushes++
+		flushed := row{instruction: fetchedAfter(execution.PC), isFlushed: true}
+		flushed.cycles[IF] = cycles[IF] + 1
+		simulator.addRow(flushed)
+		simulator.nextFetch = cycles[ID] + 1
+	}
+	simulator.previous = cycles
+}
+
+// Method to find the first cycle in which an instruction can be in ID, given the instruction
+// that produces one of its operands. Branches need their operands in ID, everything else in EX.
+func (simulator *Simulator) operandReady(source producer, isBranch bool) int64 {
+	if !simulator.forwarding {
+		// the register file is written in the first half of WB and read in the second half
+		return source.cycles[WB]
+	}
+	available := source.cycles[EX] + 1
+	if source.isLoad {
+		available = source.cycles[MEM] + 1
+	}
+	if isBranch {
+		return available
+	}
+	return available - 1
+}
+
+// Method to keep a row for the diagram.
+func (simulator *Simulator) addRow(newRow row) {
+	if len(simulator.rows) < DIAGRAM_ROWS {
+		simulator.rows = append(simulator.rows, newRow)
+	}
+}
+
+// Function to find the instruction that follows a branch in memory.
+func fetchedAfter(PC int64) string {
+	if Memory.IsValidPC(PC + Memory.INCREMENT) {
+		if instruction := Memory.InstructionMem.Instructions[PC+Memory.INCREMENT]; instruction != "" {
+			return instruction
+		}
+	}
+	return "NoOp"
+}
+
+// Cycles is a method to return the number of cycles taken to complete every instruction.
+func (simulator *Simulator) Cycles() int64 {
+	return simulator.previous[WB]
+}
+
+// ShowDiagram is a method to print which stage each instruction is in, cycle by cycle.
+// Cycles in which an instruction is held up by a stall are marked with "--".
+func (simulator *Simulator) ShowDiagram() {
+	var lastCycle int64
+	var width int64
+	for _, current := range simulator.rows {
+		lastCycle = maxInt64(lastCycle, current.cycles[IF])
+		if !current.isFlushed {
+			lastCycle = maxInt64(lastCycle, current.cycles[WB])
+		}
+		width = maxInt64(width, int64(len(current.instruction)+len(" (flushed)")))
+	}
+
+	header := fmt.Sprintf("%-*s", int(width), "Instruction")
+	for cycle := int64(1); cycle <= lastCycle; cycle++ {
+		header += fmt.Sprintf("%4d", cycle)
+	}
+	fmt.Println(header)
+
+	for _, current := range simulator.rows {
+		cells := make([]string, lastCycle+1)
+		for cycle := range cells {
+			cells[cycle] = "    "
+		}
+		if current.isFlushed {
+			cells[current.cycles[IF]] = fmt.Sprintf("%4s", stageNames[IF])
+		} else {
+			for cycle := current.cycles[IF] + 1; cycle < current.cycles[ID]; cycle++ {
+				cells[cycle] = "  --"
+			}
+			for stage, cycle := range current.cycles {
+				cells[cycle] = fmt.Sprintf("%4s", stageNames[stage])
+			}
+		}
+
+		instruction := current.instruction
+		if current.isFlushed {
+			instruction += " (flushed)"
+		}
+		fmt.Println(strings.TrimRight(fmt.Sprintf("%-*s", int(width), instruction)+strings.Join(cells[1:], ""), " "))
+	}
+
+	if simulator.instructions+simulator.flushes > int64(len(simulator.rows)) {
+		fmt.Println("... only the first", DIAGRAM_ROWS, "rows are shown")
+	}
+	fmt.Printf("\n")
+}
+
+// ShowSummary is a method to print the cycle count, stalls and CPI.
+func (simulator *Simulator) ShowSummary() {
+	forwarding := "off"
+	if simulator.forwarding {
+		forwarding = "on"
+	}
+	cpi := "-"
+	if simulator.instructions != 0 {
+		cpi = strconv.FormatFloat(float64(simulator.Cycles())/float64(simulator.instructions), 'f', 2, 64)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Pipeline", "Value"})
+	table.Append([]string{"Forwarding", forwarding})
+	table.Append([]string{"Instructions", strconv.FormatInt(simulator.instructions, 10)})
+	table.Append([]string{"Cycles", strconv.FormatInt(simulator.Cycles(), 10)})
+	table.Append([]string{"Data hazard stalls", strconv.FormatInt(simulator.stalls, 10)})
+	table.Append([]string{"Control hazard flushes", strconv.FormatInt(simulator.flushes, 10)})
+	table.Append([]string{"CPI", cpi})
+	table.Render()
+	fmt.Printf("\n")
+}
+
+// Function to return the larger of two values.
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	Memory "github.com/coderick14/ARMed/Memory"
+	"testing"
+)
+
+// Function to describe an ALU instruction that writes rd from the given sources.
+func alu(rd uint, sources ...uint) *Memory.Execution {
+	return &Memory.Execution{Instruction: "ALU", RegistersRead: sources, RegistersWritten: []Memory.RegisterWrite{{Register: rd}}}
+}
+
+// Function to describe a load of rd from the address in rn.
+func load(rd uint, rn uint) *Memory.Execution {
+	execution := alu(rd, rn)
+	execution.MemoryAccesses = []Memory.MemoryAccess{{Size: Memory.WORD_SIZE}}
+	return execution
+}
+
+// Function to describe a branch that reads the given sources.
+func branch(isTaken bool, sources ...uint) *Memory.Execution {
+	return &Memory.Execution{Instruction: "B", RegistersRead: sources, IsBranch: true, IsTaken: isTaken}
+}
+
+func TestObserve(t *testing.T) {
+	compare := &Memory.Execution{Instruction: "SUBS", RegistersRead: []uint{2, 3}, SetsFlags: true}
+	branchOnFlags := branch(false)
+	branchOnFlags.ReadsFlags = true
+
+	tests := []struct {
+		name       string
+		forwarding bool
+		executions []*Memory.Execution
+		cycles     int64
+		stalls     int64
+		flushes    int64
+	}{
+		{"independent", true, []*Memory.Execution{alu(1, 2), alu(3, 4), alu(5, 6)}, 7, 0, 0},
+		{"forwarded ALU result", true, []*Memory.Execution{alu(1, 2), alu(3, 1)}, 6, 0, 0},
+		{"ALU result without forwarding", false, []*Memory.Execution{alu(1, 2), alu(3, 1)}, 8, 2, 0},
+		{"result two instructions later without forwarding", false, []*Memory.Execution{alu(1, 2), alu(4, 5), alu(3, 1)}, 8, 1, 0},
+		{"load-use", true, []*Memory.Execution{load(1, 2), alu(3, 1)}, 7, 1, 0},
+		{"load-use without forwarding", false, []*Memory.Execution{load(1, 2), alu(3, 1)}, 8, 2, 0},
+		{"branch on an ALU result", true, []*Memory.Execution{alu(1, 2), branch(false, 1)}, 7, 1, 0},
+		{"branch on a load", true, []*Memory.Execution{load(1, 2), branch(false, 1)}, 8, 2, 0},
+		{"branch on flags", true, []*Memory.Execution{compare, branchOnFlags}, 7, 1, 0},
+		{"zero register", false, []*Memory.Execution{alu(Memory.XZR, 2), alu(3, Memory.XZR)}, 6, 0, 0},
+		{"taken branch", true, []*Memory.Execution{branch(true), alu(1, 2)}, 7, 0, 1},
+		{"branch not taken", true, []*Memory.Execution{branch(false), alu(1, 2)}, 6, 0, 0},
+	}
+	for _, test := range tests {
+		simulator := NewSimulator(test.forwarding)
+		for _, execution := range test.executions {
+			simulator.Observe(execution)
+		}
+		if simulator.Cycles() != test.cycles || simulator.stalls != test.stalls || simulator.flushes != test.flushes {
+			t.Errorf("%s: %d cycles, %d stalls and %d flushes, want %d, %d and %d", test.name,
+				simulator.Cycles(), simulator.stalls, simulator.flushes, test.cycles, test.stalls, test.flushes)
+		}
+	}
+}
+
+func TestDiagramRows(t *testing.T) {
+	simulator := NewSimulator(true)
+	for i := 0; i < DIAGRAM_ROWS+5; i++ {
+		simulator.Observe(branch(true))
+	}
+	if len(simulator.rows) != DIAGRAM_ROWS {
+		t.Errorf("%d rows kept, want %d", len(simulator.rows), DIAGRAM_ROWS)
+	}
+	if !simulator.rows[1].isFlushed || simulator.rows[1].cycles[IF] != 2 {
+		t.Errorf("second row = %+v, want the instruction flushed in cycle 2", simulator.rows[1])
+	}
+}
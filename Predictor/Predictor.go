@@ -0,0 +1,194 @@
+package predictor
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Predictor guesses whether a conditional branch is taken, and learns from the outcome.
+// PC is the number of the branch instruction.
+type Predictor interface {
+	Predict(PC int64) bool
+	Update(PC int64, isTaken bool)
+}
+
+// Struct for the static predictors, which always guess the same
+type staticPredictor struct {
+	isTaken bool
+}
+
+func (predictor *staticPredictor) Predict(PC int64) bool {
+	return predictor.isTaken
+}
+
+func (predictor *staticPredictor) Update(PC int64, isTaken bool) {
+}
+
+// Struct for a table of saturating counters indexed by the branch address.
+// With 1 bit a counter remembers the last outcome, with 2 bits it must be wrong twice to change its guess.
+type counterPredictor struct {
+	counters []int
+	maximum  int
+}
+
+func (predictor *counterPredictor) Predict(PC int64) bool {
+	return predictor.counters[PC%int64(len(predictor.counters))] > predictor.maximum/2
+}
+
+func (predictor *counterPredictor) Update(PC int64, isTaken bool) {
+	counter := &predictor.counters[PC%int64(len(predictor.counters))]
+	*counter = updateCounter(*counter, predictor.maximum, isTaken)
+}
+
+// Struct for the gshare predictor, which indexes 2-bit counters with the branch address
+// XOR the outcomes of the most recent branches.
+type gsharePredictor struct {
+	counters []int
+	history  int64
+	bits     uint
+}
+
+func (predictor *gsharePredictor) index(PC int64) int64 {
+	return (PC ^ predictor.history) % int64(len(predictor.counters))
+}
+
+func (predictor *gsharePredictor) Predict(PC int64) bool {
+	return predictor.counters[predictor.index(PC)] > 1
+}
+
+func (predictor *gsharePredictor) Update(PC int64, isTaken bool) {
+	counter := &predictor.counters[predictor.index(PC)]
+	*counter = updateCounter(*counter, 3, isTaken)
+
+	predictor.history = (predictor.history << 1) & (1<<predictor.bits - 1)
+	if isTaken {
+		predictor.history |= 1
+	}
+}
+
+// Function to count up on a taken branch and down otherwise, staying between 0 and maximum.
+func updateCounter(counter int, maximum int, isTaken bool) int {
+	if isTaken && counter < maximum {
+		return counter + 1
+	}
+	if !isTaken && counter > 0 {
+		return counter - 1
+	}
+	return counter
+}
+
+// BTB is a direct mapped branch target buffer, which remembers where taken branches went.
+type BTB struct {
+	entries []btbEntry
+	Hits    int64
+	Misses  int64
+}
+
+// Struct to represent an entry of the branch target buffer
+type btbEntry struct {
+	isValid bool
+	PC      int64
+	target  int64
+}
+
+// NewBTB is a function to create an empty branch target buffer.
+func NewBTB(entries int64) *BTB {
+	return &BTB{entries: make([]btbEntry, entries)}
+}
+
+// Lookup is a method to find the target of a branch, if the buffer holds it.
+func (btb *BTB) Lookup(PC int64) (int64, bool) {
+	entry := btb.entries[PC%int64(len(btb.entries))]
+	if entry.isValid && entry.PC == PC {
+		btb.Hits++
+		return entry.target, true
+	}
+	btb.Misses++
+	return 0, false
+}
+
+// Update is a method to remember the target of a taken branch.
+func (btb *BTB) Update(PC int64, target int64) {
+	btb.entries[PC%int64(len(btb.entries))] = btbEntry{true, PC, target}
+}
+
+// Config describes a predictor given on the command line, such as "gshare,entries=256,history=8,btb=16".
+type Config struct {
+	Name       string
+	Entries    int64
+	History    int64
+	BTBEntries int64
+}
+
+// ParseConfig is a function to read a predictor specification.
+// The name is one of taken, not-taken, 1bit, 2bit or gshare.
+func ParseConfig(spec string) (Config, error) {
+	settings := strings.Split(spec, ",")
+	config := Config{Name: strings.ToLower(strings.TrimSpace(settings[0])), Entries: 16, History: 4}
+	switch config.Name {
+	case "taken", "not-taken", "1bit", "2bit", "gshare":
+	default:
+		return config, errors.New("Unknown branch predictor " + config.Name + ", expected taken, not-taken, 1bit, 2bit or gshare")
+	}
+
+	for _, setting := range settings[1:] {
+		parts := strings.SplitN(strings.TrimSpace(setting), "=", 2)
+		if len(parts) != 2 {
+			return config, errors.New("Expected key=value, found " + setting + " in predictor specification " + spec)
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil || value < 0 {
+			return config, errors.New("Invalid value " + parts[1] + " in predictor specification " + spec)
+		}
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "entries":
+			config.Entries = value
+		case "history":
+			config.History = value
+		case "btb":
+			config.BTBEntries = value
+		default:
+			return config, errors.New("Unknown setting " + parts[0] + " in predictor specification " + spec)
+		}
+	}
+
+	if config.Entries == 0 {
+		return config, errors.New("A predictor needs at least one entry in " + spec)
+	}
+	if config.History > 30 {
+		return config, errors.New("At most 30 bits of history are supported in " + spec)
+	}
+	return config, nil
+}
+
+// New is a method to create the predictor a configuration describes.
+func (config Config) New() Predictor {
+	switch config.Name {
+	case "taken", "not-taken":
+		return &staticPredictor{isTaken: config.Name == "taken"}
+	case "1bit":
+		return &counterPredictor{counters: make([]int, config.Entries), maximum: 1}
+	case "2bit":
+		return &counterPredictor{counters: make([]int, config.Entries), maximum: 3}
+	}
+	return &gsharePredictor{counters: make([]int, config.Entries), bits: uint(config.History)}
+}
+
+// String is a method to describe a predictor, e.g. "gshare (256 entries, 8 bits of history, 16 entry BTB)".
+func (config Config) String() string {
+	var details []string
+	switch config.Name {
+	case "1bit", "2bit":
+		details = append(details, strconv.FormatInt(config.Entries, 10)+" entries")
+	case "gshare":
+		details = append(details, strconv.FormatInt(config.Entries, 10)+" entries", strconv.FormatInt(config.History, 10)+" bits of history")
+	}
+	if config.BTBEntries != 0 {
+		details = append(details, strconv.FormatInt(config.BTBEntries, 10)+" entry BTB")
+	}
+	if len(details) == 0 {
+		return config.Name
+	}
+	return config.Name + " (" + strings.Join(details, ", ") + ")"
+}
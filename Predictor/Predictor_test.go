@@ -0,0 +1,138 @@
+package predictor
+
+import (
+	Memory "github.com/coderick14/ARMed/Memory"
+	"testing"
+)
+
+// Function to build a sequence of branch outcomes from a pattern such as "TTTN", repeated.
+func outcomes(pattern string, repeat int) []bool {
+	var result []bool
+	for i := 0; i < repeat; i++ {
+		for _, outcome := range pattern {
+			result = append(result, outcome == 'T')
+		}
+	}
+	return result
+}
+
+// Function to run a single conditional branch at PC 5, which jumps back to PC 0, through a simulator.
+func simulate(t *testing.T, spec string, taken []bool) *Simulator {
+	config, err := ParseConfig(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simulator := NewSimulator([]Config{config})
+	for _, isTaken := range taken {
+		simulator.Observe(&Memory.Execution{PC: 5, IsConditional: true, IsBranch: true, IsTaken: isTaken, Target: 0})
+	}
+	return simulator
+}
+
+func TestPredictors(t *testing.T) {
+	loop := outcomes("TTTN", 3)
+	alternating := outcomes("TN", 4)
+	tests := []struct {
+		spec  string
+		taken []bool
+		want  int64
+	}{
+		{"taken", loop, 9},
+		{"not-taken", loop, 3},
+		// wrong on the first taken branch of every iteration and on the exit
+		{"1bit", loop, 6},
+		// wrong twice while learning, then only on the exit
+		{"2bit", loop, 7},
+		{"2bit", alternating, 4},
+		// history tells the two outcomes apart once both counters are trained
+		{"gshare,history=1", alternating, 6},
+		// a predicted taken branch is only correct once the BTB knows its target
+		{"taken,btb=4", outcomes("T", 3), 2},
+		{"not-taken,btb=4", outcomes("N", 3), 3},
+	}
+	for _, test := range tests {
+		simulator := simulate(t, test.spec, test.taken)
+		evaluation := simulator.evaluations[0]
+		if evaluation.correct != test.want {
+			t.Errorf("%s: %d of %d correct, want %d", test.spec, evaluation.correct, len(test.taken), test.want)
+		}
+		if site := simulator.sites[5]; site.executed != int64(len(test.taken)) || site.correct[0] != test.want {
+			t.Errorf("%s: branch at PC 5 executed %d times with %d correct, want %d and %d", test.spec, site.executed, site.correct[0], len(test.taken), test.want)
+		}
+	}
+}
+
+func TestCounterAliasing(t *testing.T) {
+	// with a single entry, two branches share a counter
+	config, _ := ParseConfig("1bit,entries=1")
+	predictor := config.New()
+	predictor.Update(0, true)
+	if !predictor.Predict(1) {
+		t.Errorf("branch 1 predicted not taken, want the outcome of branch 0")
+	}
+	config, _ = ParseConfig("1bit,entries=2")
+	predictor = config.New()
+	predictor.Update(0, true)
+	if predictor.Predict(1) {
+		t.Errorf("branch 1 predicted taken, want its own counter")
+	}
+}
+
+func TestBTB(t *testing.T) {
+	btb := NewBTB(4)
+	if _, isHit := btb.Lookup(1); isHit {
+		t.Errorf("empty BTB hit")
+	}
+	btb.Update(1, 10)
+	if target, isHit := btb.Lookup(1); !isHit || target != 10 {
+		t.Errorf("Lookup(1) = %d, %t, want 10, true", target, isHit)
+	}
+	// branch 5 maps to the entry of branch 1 and replaces it
+	btb.Update(5, 20)
+	if _, isHit := btb.Lookup(1); isHit {
+		t.Errorf("Lookup(1) hit after branch 5 replaced it")
+	}
+	if btb.Hits != 1 || btb.Misses != 2 {
+		t.Errorf("%d hits and %d misses, want 1 and 2", btb.Hits, btb.Misses)
+	}
+}
+
+func TestUnconditionalBranches(t *testing.T) {
+	simulator := simulate(t, "2bit", nil)
+	simulator.Observe(&Memory.Execution{PC: 3, IsBranch: true, IsTaken: true})
+	if simulator.branches != 0 || len(simulator.sites) != 0 {
+		t.Errorf("unconditional branch counted, want only B.cond, CBZ and CBNZ")
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := map[string]string{
+		"taken":                               "taken",
+		"Not-Taken":                           "not-taken",
+		"2bit":                                "2bit (16 entries)",
+		"1bit, entries=64, btb=8":             "1bit (64 entries, 8 entry BTB)",
+		"gshare,entries=256,history=8,btb=16": "gshare (256 entries, 8 bits of history, 16 entry BTB)",
+	}
+	for spec, want := range tests {
+		config, err := ParseConfig(spec)
+		if err != nil {
+			t.Errorf("ParseConfig(%q) failed: %v", spec, err)
+		} else if config.String() != want {
+			t.Errorf("ParseConfig(%q) = %s, want %s", spec, config, want)
+		}
+	}
+
+	errorTests := []string{
+		"perceptron",
+		"2bit,entries=0",
+		"2bit,entries=-1",
+		"2bit,entries",
+		"2bit,size=4",
+		"gshare,history=31",
+	}
+	for _, spec := range errorTests {
+		if _, err := ParseConfig(spec); err == nil {
+			t.Errorf("ParseConfig(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package predictor
+
+import (
+	"fmt"
+	Memory "github.com/coderick14/ARMed/Memory"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Struct to hold a predictor together with its branch target buffer and results
+type evaluation struct {
+	config    Config
+	predictor Predictor
+	btb       *BTB
+	correct   int64
+}
+
+// Struct to count the outcomes of a single conditional branch instruction
+type site struct {
+	executed int64
+	taken    int64
+	correct  []int64
+}
+
+// Simulator runs branch predictors alongside the program on every B.cond, CBZ and CBNZ.
+// A prediction is correct if it guessed the direction right and, for a branch predicted
+// taken with a BTB, the BTB knew the target.
+type Simulator struct {
+	evaluations []*evaluation
+	sites       map[int64]*site
+	branches    int64
+	taken       int64
+}
+
+// NewSimulator is a function to create a simulator that compares the given predictors.
+func NewSimulator(configs []Config) *Simulator {
+	simulator := Simulator{sites: make(map[int64]*site)}
+	for _, config := range configs {
+		current := evaluation{config: config, predictor: config.New()}
+		if config.BTBEntries != 0 {
+			current.btb = NewBTB(config.BTBEntries)
+		}
+		simulator.evaluations = append(simulator.evaluations, &current)
+	}
+	return &simulator
+}
+
+// Observe is a method to let every predictor guess the outcome of a conditional branch, and then learn it.
+func (simulator *Simulator) Observe(execution *Memory.Execution) {
+	if !execution.IsConditional {
+		return
+	}
+	branch, isKnown := simulator.sites[execution.PC]
+	if !isKnown {
+		branch = &site{correct: make([]int64, len(simulator.evaluations))}
+		simulator.sites[execution.PC] = branch
+	}
+	branch.executed++
+	simulator.branches++
+	if execution.IsTaken {
+		branch.taken++
+		simulator.taken++
+	}
+
+	for i, current := range simulator.evaluations {
+		isPredictedTaken := current.predictor.Predict(execution.PC)
+		isCorrect := isPredictedTaken == execution.IsTaken
+		if current.btb != nil {
+			if isPredictedTaken {
+				target, isHit := current.btb.Lookup(execution.PC)
+				isCorrect = isCorrect && isHit && target == execution.Target
+			}
+			if execution.IsTaken {
+				current.btb.Update(execution.PC, execution.Target)
+			}
+		}
+		current.predictor.Update(execution.PC, execution.IsTaken)
+
+		if isCorrect {
+			current.correct++
+			branch.correct[i]++
+		}
+	}
+}
+
+// Function to format a fraction as a percentage.
+func percentage(count int64, total int64) string {
+	if total == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(100*float64(count)/float64(total), 'f', 2, 64) + "%"
+}
+
+// ShowAccuracy is a method to pretty print the accuracy of every predictor, for each branch and overall.
+func (simulator *Simulator) ShowAccuracy() {
+	header := []string{"Branch", "Instruction", "Executed", "Taken"}
+	for i, current := range simulator.evaluations {
+		fmt.Println("Predictor", i+1, ":", current.config)
+		header = append(header, "Predictor "+strconv.Itoa(i+1))
+	}
+
+	var PCs []int64
+	for PC := range simulator.sites {
+		PCs = append(PCs, PC)
+	}
+	sort.Slice(PCs, func(i, j int) bool { return PCs[i] < PCs[j] })
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(header)
+	for _, PC := range PCs {
+		branch := simulator.sites[PC]
+		row := []string{
+			Memory.InstructionMem.Locations[PC].String(),
+			Memory.InstructionMem.Instructions[PC],
+			strconv.FormatInt(branch.executed, 10),
+			percentage(branch.taken, branch.executed),
+		}
+		for _, correct := range branch.correct {
+			row = append(row, percentage(correct, branch.executed))
+		}
+		table.Append(row)
+	}
+
+	footer := []string{"Overall", "-", strconv.FormatInt(simulator.branches, 10), percentage(simulator.taken, simulator.branches)}
+	for _, current := range simulator.evaluations {
+		footer = append(footer, percentage(current.correct, simulator.branches))
+	}
+	table.SetFooter(footer)
+	table.Render()
+
+	for i, current := range simulator.evaluations {
+		if current.btb != nil {
+			fmt.Printf("Predictor %d BTB : %d hits, %d misses\n", i+1, current.btb.Hits, current.btb.Misses)
+		}
+	}
+	fmt.Printf("\n")
+}
@@ -0,0 +1,148 @@
+package profiler
+
+import (
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Memory "github.com/coderick14/ARMed/Memory"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ENTRY_FUNCTION names the code that runs before the first function is called
+const ENTRY_FUNCTION = "main"
+
+// HOT_SPOTS is the number of source lines shown in the hot-spot list
+const HOT_SPOTS = 20
+
+// Profiler counts how often each source line runs and how many instructions each function executes.
+// An instruction belongs to the innermost call that has not returned when it runs, or to main outside of any call.
+// The stack is copied from Memory.CallStack after every instruction, as the one the next instruction runs in.
+type Profiler struct {
+	lineCounts     map[Assembler.Location]int64
+	lineText       map[Assembler.Location]string
+	functionCounts map[string]int64
+	calls          map[string]int64
+	stacks         map[string]int64
+	stack          []string
+	instructions   int64
+}
+
+// New is a function to create a profiler for the loaded program.
+func New() *Profiler {
+	profiler := Profiler{
+		lineCounts:     make(map[Assembler.Location]int64),
+		lineText:       make(map[Assembler.Location]string),
+		functionCounts: make(map[string]int64),
+		calls:          make(map[string]int64),
+		stacks:         make(map[string]int64),
+		stack:          []string{ENTRY_FUNCTION},
+	}
+	return &profiler
+}
+
+// Observe is a method to count an executed instruction for its line, its function and its call stack.
+func (profiler *Profiler) Observe(execution *Memory.Execution) {
+	location := Memory.InstructionMem.Locations[execution.PC]
+	profiler.lineCounts[location]++
+	if _, isKnown := profiler.lineText[location]; !isKnown {
+		profiler.lineText[location] = Memory.InstructionMem.Describe(execution.PC)
+	}
+
+	profiler.functionCounts[profiler.stack[len(profiler.stack)-1]]++
+	profiler.stacks[strings.Join(profiler.stack, ";")]++
+	profiler.instructions++
+
+	if execution.Mnemonic == "BL" && len(Memory.CallStack) != 0 {
+		profiler.calls[Memory.CallStack[len(Memory.CallStack)-1].Function]++
+	}
+	profiler.stack = []string{ENTRY_FUNCTION}
+	for _, caller := range Memory.CallStack {
+		profiler.stack = append(profiler.stack, caller.Function)
+	}
+}
+
+// Method to format a count as a percentage of all executed instructions.
+func (profiler *Profiler) share(count int64) string {
+	if profiler.instructions == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(100*float64(count)/float64(profiler.instructions), 'f', 2, 64) + "%"
+}
+
+// ShowProfile is a method to pretty print the instructions executed by each function,
+// followed by the most executed source lines.
+func (profiler *Profiler) ShowProfile() {
+	var functions []string
+	for function := range profiler.functionCounts {
+		functions = append(functions, function)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		first, second := profiler.functionCounts[functions[i]], profiler.functionCounts[functions[j]]
+		return first > second || (first == second && functions[i] < functions[j])
+	})
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Function", "Calls", "Instructions", "Share"})
+	for _, function := range functions {
+		count := profiler.functionCounts[function]
+		table.Append([]string{function, strconv.FormatInt(profiler.calls[function], 10), strconv.FormatInt(count, 10), profiler.share(count)})
+	}
+	table.Render()
+	fmt.Printf("\n")
+
+	var locations []Assembler.Location
+	for location := range profiler.lineCounts {
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		first, second := profiler.lineCounts[locations[i]], profiler.lineCounts[locations[j]]
+		if first != second {
+			return first > second
+		}
+		if locations[i].File != locations[j].File {
+			return locations[i].File < locations[j].File
+		}
+		return locations[i].Line < locations[j].Line
+	})
+	if len(locations) > HOT_SPOTS {
+		locations = locations[:HOT_SPOTS]
+	}
+
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Rank", "Line", "Instruction", "Instructions executed", "Share"})
+	for i, location := range locations {
+		count := profiler.lineCounts[location]
+		table.Append([]string{strconv.Itoa(i + 1), location.String(), profiler.lineText[location], strconv.FormatInt(count, 10), profiler.share(count)})
+	}
+	table.Render()
+	fmt.Printf("\n")
+}
+
+// WriteFoldedStacks is a method to write the call stacks in the folded format read by flame graph tools.
+// Each line is a stack from the outermost function to the innermost, separated by ';',
+// followed by the number of instructions executed in it.
+func (profiler *Profiler) WriteFoldedStacks(fileName string) error {
+	var stacks []string
+	for stack := range profiler.stacks {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return errors.New("Error creating file : " + err.Error())
+	}
+	defer file.Close()
+
+	for _, stack := range stacks {
+		_, err = fmt.Fprintln(file, stack, profiler.stacks[stack])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
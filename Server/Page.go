@@ -0,0 +1,114 @@
+package server
+
+// The page served at "/", which draws the state returned by the JSON API
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ARMed</title>
+<style>
+body { font-family: monospace; margin: 1em; }
+button { font-family: monospace; margin-right: 0.5em; }
+#panes { display: flex; gap: 1.5em; align-items: flex-start; margin-top: 1em; }
+.pane { border: 1px solid #aaa; padding: 0.5em; }
+#source, #memory { height: 32em; overflow-y: scroll; }
+table { border-collapse: collapse; }
+td { padding: 0 0.6em; white-space: pre; }
+#source tr { cursor: pointer; }
+.current { background: #ffe680; }
+.breakpoint td:first-child { color: #fff; background: #c33; }
+.changed { background: #9fdf9f; }
+.set { font-weight: bold; }
+.error { color: #c33; }
+</style>
+</head>
+<body>
+<div>
+<button onclick="act('step')">Step</button>
+<button onclick="act('run')">Run</button>
+<button onclick="act('reset')">Reset</button>
+<span id="status"></span>
+</div>
+<div id="panes">
+<div class="pane"><b>Source</b> (click a line to toggle a breakpoint)<div id="source"></div></div>
+<div class="pane"><b>Registers</b><div id="registers"></div><p><b>Flags</b> <span id="flags"></span></p></div>
+<div class="pane"><b>Memory</b> from <input id="start" size="8" value="0" onchange="loadMemory()">
+<div id="memory"></div></div>
+</div>
+<script>
+function escape(text) {
+  return text.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;");
+}
+
+function show(state) {
+  var status = state.status + ", " + state.steps + " instructions executed";
+  if (state.error) {
+    status += " <span class='error'>" + escape(state.error) + "</span>";
+  }
+  document.getElementById("status").innerHTML = status;
+
+  var rows = "";
+  state.instructions.forEach(function (instruction) {
+    var classes = [];
+    if (instruction.pc == state.pc) classes.push("current");
+    if (instruction.breakpoint) classes.push("breakpoint");
+    rows += "<tr id='pc" + instruction.pc + "' class='" + classes.join(" ") + "' onclick='toggle(" + instruction.pc + ")'>" +
+      "<td>" + instruction.pc + "</td><td>" + escape(instruction.location) + "</td><td>" + escape(instruction.text) + "</td></tr>";
+  });
+  document.getElementById("source").innerHTML = "<table>" + rows + "</table>";
+  var current = document.getElementById("pc" + state.pc);
+  if (current) current.scrollIntoView({block: "nearest"});
+
+  var changed = state.changed || [];
+  rows = "";
+  for (var i = 0; i < 32; i++) {
+    var name = i == 28 ? "SP" : i == 29 ? "FP" : i == 30 ? "LR" : i == 31 ? "XZR" : "X" + i;
+    var cell = changed.indexOf(i) >= 0 ? " class='changed'" : "";
+    rows += "<tr><td>" + name + "</td><td" + cell + ">" + state.registers[i] + "</td></tr>";
+  }
+  document.getElementById("registers").innerHTML = "<table>" + rows + "</table>";
+
+  var flags = "";
+  ["N", "Z", "V", "C"].forEach(function (flag) {
+    flags += "<span" + (state.flags[flag] ? " class='set'>" : ">") + flag + "=" + (state.flags[flag] ? 1 : 0) + "</span> ";
+  });
+  document.getElementById("flags").innerHTML = flags;
+  loadMemory();
+}
+
+function loadMemory() {
+  var start = document.getElementById("start").value;
+  fetch("/api/memory?count=256&start=" + encodeURIComponent(start)).then(function (response) {
+    return response.json();
+  }).then(function (memory) {
+    var rows = "";
+    (memory.words || []).forEach(function (word) {
+      var mark = word.address == memory.sp ? " &lt;- SP" : "";
+      rows += "<tr><td>" + word.address + "</td><td>" + word.value + "</td><td>" + mark + "</td></tr>";
+    });
+    document.getElementById("memory").innerHTML = "<table>" + rows + "</table>";
+  });
+}
+
+function request(url, method) {
+  fetch(url, {method: method}).then(function (response) {
+    if (!response.ok) {
+      return response.text().then(function (text) { alert(text); });
+    }
+    return response.json().then(show);
+  });
+}
+
+function act(action) {
+  request("/api/" + action, "POST");
+}
+
+function toggle(pc) {
+  request("/api/breakpoint?pc=" + pc, "POST");
+}
+
+request("/api/state", "GET");
+</script>
+</body>
+</html>
+`
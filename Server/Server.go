@@ -0,0 +1,291 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Limits "github.com/coderick14/ARMed/Limits"
+	Memory "github.com/coderick14/ARMed/Memory"
+	Watch "github.com/coderick14/ARMed/Watch"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Status of the program shown by the server
+const (
+	READY_STATUS    = "ready"
+	FINISHED_STATUS = "finished"
+	ERROR_STATUS    = "error"
+	LIMIT_STATUS    = "limit"
+)
+
+// RUN_LIMIT is the number of instructions a single run may execute before it pauses
+const RUN_LIMIT = 1000000
+
+// DEFAULT_HOST is the host the server listens on when the address does not name one
+const DEFAULT_HOST = "127.0.0.1"
+
+// Number of words shown by the memory view when the request does not say
+const MEMORY_VIEW_WORDS = 64
+
+// Instruction is an instruction of the program as shown in the source view.
+type Instruction struct {
+	PC           int64  `json:"pc"`
+	Text         string `json:"text"`
+	Location     string `json:"location"`
+	IsBreakpoint bool   `json:"breakpoint"`
+}
+
+// State is the state of the machine returned by the JSON API.
+// Changed lists the registers written by the last step or run.
+type State struct {
+	PC           int64           `json:"pc"`
+	Status       string          `json:"status"`
+	Error        string          `json:"error,omitempty"`
+	Steps        int64           `json:"steps"`
+	Registers    [32]int64       `json:"registers"`
+	Changed      []int           `json:"changed"`
+	Flags        map[string]bool `json:"flags"`
+	Instructions []Instruction   `json:"instructions"`
+}
+
+// Server lets a program be stepped through from a web page, using a JSON API:
+//
+//	GET  /api/state                   the state of the machine
+//	POST /api/step                    execute one instruction
+//	POST /api/run                     run to the end, to the next breakpoint or to a break watchpoint
+//	POST /api/reset                   load the program again
+//	POST /api/breakpoint?pc=N         set or clear a breakpoint at instruction N
+//	GET  /api/memory?start=A&count=N  N words of data memory from byte address A
+type Server struct {
+	sync.Mutex
+	program     *Assembler.Object
+	setup       func() error
+	breakpoints map[int64]bool
+	watcher     *Watch.Watcher
+	monitor     *Limits.Monitor
+	previous    [32]int64
+	status      string
+	err         string
+	steps       int64
+}
+
+// New is a function to create a server for a linked program, and load the program.
+// Setup sets the inputs of the program each time it is loaded, and may be nil.
+// Run also stops when a break watchpoint of the watcher triggers. The watcher may be nil.
+// The program is stopped for good once it reaches a limit of the monitor, until it is loaded again.
+// The monitor may be nil.
+func New(program *Assembler.Object, setup func() error, watcher *Watch.Watcher, monitor *Limits.Monitor) (*Server, error) {
+	server := Server{program: program, setup: setup, breakpoints: make(map[int64]bool), watcher: watcher, monitor: monitor}
+	return &server, server.reset()
+}
+
+// ListenAndServe is a method to serve the page and the API on an address such as ":8080".
+// An address without a host is served on 127.0.0.1 only, so that the program is not shared with the network.
+func (server *Server) ListenAndServe(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.New("Invalid address " + address + ", expected [HOST]:PORT such as :8080")
+	}
+	if host == "" {
+		address = net.JoinHostPort(DEFAULT_HOST, port)
+	}
+
+	http.HandleFunc("/", server.handlePage)
+	http.HandleFunc("/api/state", server.handle(false, func(request *http.Request) error { return nil }))
+	http.HandleFunc("/api/step", server.handle(true, server.step))
+	http.HandleFunc("/api/run", server.handle(true, server.run))
+	http.HandleFunc("/api/reset", server.handle(true, func(request *http.Request) error { return server.reset() }))
+	http.HandleFunc("/api/breakpoint", server.handle(true, server.toggleBreakpoint))
+	http.HandleFunc("/api/memory", server.handleMemory)
+	fmt.Println("Serving ARMed on http://" + address)
+	return http.ListenAndServe(address, nil)
+}
+
+// Method to load the program again and start from its first instruction.
+func (server *Server) reset() error {
+	Memory.ResetMachine()
+	err := Memory.LoadProgram(server.program)
+	if err != nil {
+		return err
+	}
+	Memory.InitRegisters()
+	if server.setup != nil {
+		err = server.setup()
+		if err != nil {
+			return err
+		}
+	}
+	if server.monitor != nil {
+		server.monitor.Reset()
+	}
+	server.saveRegisters()
+	server.status, server.err, server.steps = READY_STATUS, "", 0
+	server.updateStatus()
+	return nil
+}
+
+// Method to remember the registers before an instruction, to show which ones change.
+func (server *Server) saveRegisters() {
+	for i := range server.previous {
+		server.previous[i] = Memory.GetRegisterValue(uint(i))
+	}
+}
+
+// Method to mark the program as finished once it runs past its last instruction.
+func (server *Server) updateStatus() {
+	if server.status == READY_STATUS && !Memory.IsValidPC(Memory.InstructionMem.PC) {
+		server.status = FINISHED_STATUS
+	}
+}
+
+// Method to execute a single instruction.
+func (server *Server) executeOne() {
+	location := Memory.InstructionMem.Locations[Memory.InstructionMem.PC]
+	err := Memory.InstructionMem.ValidateAndExecuteInstruction()
+	server.steps++
+	if err != nil {
+		server.status, server.err = ERROR_STATUS, location.String()+": "+err.Error()
+	}
+	server.updateStatus()
+	if server.status != READY_STATUS || server.monitor == nil {
+		return
+	}
+	if violation := server.monitor.Violation(); violation != nil {
+		server.status, server.err = LIMIT_STATUS, violation.Message
+		if where := server.monitor.Where(); where != "" {
+			server.err += ", last loop " + where
+		}
+	}
+}
+
+// Method to check if a break watchpoint triggered since the last instruction.
+func (server *Server) isWatchBreak() bool {
+	return server.watcher != nil && server.watcher.ShouldBreak()
+}
+
+func (server *Server) step(request *http.Request) error {
+	if server.status == READY_STATUS {
+		server.saveRegisters()
+		server.executeOne()
+		server.isWatchBreak()
+	}
+	return nil
+}
+
+func (server *Server) run(request *http.Request) error {
+	if server.status != READY_STATUS {
+		return nil
+	}
+	server.saveRegisters()
+	for i := 0; i < RUN_LIMIT && server.status == READY_STATUS; i++ {
+		server.executeOne()
+		if server.breakpoints[Memory.InstructionMem.PC] || server.isWatchBreak() {
+			break
+		}
+	}
+	return nil
+}
+
+func (server *Server) toggleBreakpoint(request *http.Request) error {
+	PC, err := strconv.ParseInt(request.URL.Query().Get("pc"), 10, 64)
+	if err != nil || !Memory.IsValidPC(PC) {
+		return fmt.Errorf("Invalid instruction number %s", request.URL.Query().Get("pc"))
+	}
+	server.breakpoints[PC] = !server.breakpoints[PC]
+	return nil
+}
+
+// Method to collect the state of the machine.
+func (server *Server) state() State {
+	flags := Memory.GetFlags()
+	state := State{
+		PC:     Memory.InstructionMem.PC,
+		Status: server.status,
+		Error:  server.err,
+		Steps:  server.steps,
+		Flags:  map[string]bool{"N": flags[0], "Z": flags[1], "V": flags[2], "C": flags[3]},
+	}
+	for i := range state.Registers {
+		state.Registers[i] = Memory.GetRegisterValue(uint(i))
+		if state.Registers[i] != server.previous[i] {
+			state.Changed = append(state.Changed, i)
+		}
+	}
+	for PC, text := range Memory.InstructionMem.Instructions {
+		if text == "" {
+			continue
+		}
+		state.Instructions = append(state.Instructions, Instruction{
+			PC:           int64(PC),
+			Text:         Memory.InstructionMem.Describe(int64(PC)),
+			Location:     Memory.InstructionMem.Locations[PC].String(),
+			IsBreakpoint: server.breakpoints[int64(PC)],
+		})
+	}
+	return state
+}
+
+// Method to wrap an API action in a handler that runs it and responds with the new state.
+// Actions that change the machine must be sent with POST.
+func (server *Server) handle(isAction bool, action func(request *http.Request) error) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if isAction && request.Method != http.MethodPost {
+			http.Error(writer, "Use POST for "+request.URL.Path, http.StatusMethodNotAllowed)
+			return
+		}
+		server.Lock()
+		defer server.Unlock()
+		if err := action(request); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(writer, server.state())
+	}
+}
+
+// Method to send words of data memory. A start outside of data memory is clamped to it,
+// and at most the whole of data memory is sent.
+func (server *Server) handleMemory(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	start, err := strconv.ParseInt(query.Get("start"), 0, 64)
+	if err != nil || start < 0 {
+		start = 0
+	}
+	if start > Memory.MEMORY_SIZE*Memory.WORD_SIZE {
+		start = Memory.MEMORY_SIZE * Memory.WORD_SIZE
+	}
+	count, err := strconv.ParseInt(query.Get("count"), 10, 64)
+	if err != nil || count <= 0 {
+		count = MEMORY_VIEW_WORDS
+	}
+	if count > Memory.MEMORY_SIZE {
+		count = Memory.MEMORY_SIZE
+	}
+
+	server.Lock()
+	defer server.Unlock()
+	words := []map[string]int64{}
+	for index := start / Memory.WORD_SIZE; index < Memory.MEMORY_SIZE && int64(len(words)) < count; index++ {
+		words = append(words, map[string]int64{"address": index * Memory.WORD_SIZE, "value": int64(Memory.GetMemoryWord(index))})
+	}
+	writeJSON(writer, map[string]interface{}{"sp": Memory.GetRegisterValue(Memory.SP), "words": words})
+}
+
+func (server *Server) handlePage(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		http.NotFound(writer, request)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(writer, page)
+}
+
+// Function to send a value as JSON.
+func writeJSON(writer http.ResponseWriter, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(value)
+}
@@ -0,0 +1,113 @@
+package statistics
+
+import (
+	"encoding/json"
+	"fmt"
+	Memory "github.com/coderick14/ARMed/Memory"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Statistics counts what a program did while it ran.
+// Pseudo-instructions are counted as the instructions they expand to.
+// The stack depth is measured in bytes below the value SP had when the program started.
+type Statistics struct {
+	Instructions  int64            `json:"instructions"`
+	Mnemonics     map[string]int64 `json:"mnemonics"`
+	Classes       map[string]int64 `json:"classes"`
+	BytesRead     int64            `json:"bytes_read"`
+	BytesWritten  int64            `json:"bytes_written"`
+	MaxStackDepth int64            `json:"max_stack_depth"`
+	stackTop      int64
+	hasStackTop   bool
+}
+
+// New is a function to create empty statistics.
+func New() *Statistics {
+	statistics := Statistics{Mnemonics: make(map[string]int64), Classes: make(map[string]int64)}
+	for _, class := range Memory.Classes {
+		statistics.Classes[class] = 0
+	}
+	return &statistics
+}
+
+// Observe is a method to count an executed instruction.
+func (statistics *Statistics) Observe(execution *Memory.Execution) {
+	statistics.Instructions++
+	statistics.Mnemonics[execution.Mnemonic]++
+	statistics.Classes[execution.Class()]++
+
+	for _, access := range execution.MemoryAccesses {
+		if access.IsWrite {
+			statistics.BytesWritten += access.Size
+		} else {
+			statistics.BytesRead += access.Size
+		}
+	}
+
+	for _, write := range execution.RegistersWritten {
+		if write.Register != Memory.SP {
+			continue
+		}
+		if !statistics.hasStackTop {
+			statistics.stackTop, statistics.hasStackTop = write.OldValue, true
+		}
+		if depth := statistics.stackTop - write.NewValue; depth > statistics.MaxStackDepth {
+			statistics.MaxStackDepth = depth
+		}
+	}
+}
+
+// ShowTable is a method to pretty print the statistics to terminal.
+// Mnemonics are listed from the most to the least executed.
+func (statistics *Statistics) ShowTable() {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Statistic", "Value"})
+	table.Append([]string{"Instructions", strconv.FormatInt(statistics.Instructions, 10)})
+	table.Append([]string{"Bytes read", strconv.FormatInt(statistics.BytesRead, 10)})
+	table.Append([]string{"Bytes written", strconv.FormatInt(statistics.BytesWritten, 10)})
+	table.Append([]string{"Maximum stack depth", strconv.FormatInt(statistics.MaxStackDepth, 10) + " bytes"})
+	table.Render()
+	fmt.Printf("\n")
+
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Class", "Count", "Share"})
+	for _, class := range Memory.Classes {
+		table.Append([]string{class, strconv.FormatInt(statistics.Classes[class], 10), statistics.share(statistics.Classes[class])})
+	}
+	table.Render()
+	fmt.Printf("\n")
+
+	var mnemonics []string
+	for mnemonic := range statistics.Mnemonics {
+		mnemonics = append(mnemonics, mnemonic)
+	}
+	sort.Slice(mnemonics, func(i, j int) bool {
+		first, second := statistics.Mnemonics[mnemonics[i]], statistics.Mnemonics[mnemonics[j]]
+		return first > second || (first == second && mnemonics[i] < mnemonics[j])
+	})
+
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Mnemonic", "Count", "Share"})
+	for _, mnemonic := range mnemonics {
+		table.Append([]string{mnemonic, strconv.FormatInt(statistics.Mnemonics[mnemonic], 10), statistics.share(statistics.Mnemonics[mnemonic])})
+	}
+	table.Render()
+	fmt.Printf("\n")
+}
+
+// ShowJSON is a method to print the statistics as a JSON object.
+func (statistics *Statistics) ShowJSON() {
+	output, _ := json.MarshalIndent(statistics, "", "  ")
+	fmt.Println(string(output))
+}
+
+// Method to format a count as a percentage of all executed instructions.
+func (statistics *Statistics) share(count int64) string {
+	if statistics.Instructions == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(100*float64(count)/float64(statistics.Instructions), 'f', 2, 64) + "%"
+}
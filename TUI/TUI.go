@@ -0,0 +1,455 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	Limits "github.com/coderick14/ARMed/Limits"
+	Memory "github.com/coderick14/ARMed/Memory"
+	Watch "github.com/coderick14/ARMed/Watch"
+	color "github.com/fatih/color"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+)
+
+// Size of the screen when the terminal does not say
+const (
+	DEFAULT_ROWS    = 24
+	DEFAULT_COLUMNS = 80
+)
+
+// Width of the column showing registers, flags, stack and memory
+const RIGHT_WIDTH = 44
+
+// Rows taken by the registers and flags, including their headers
+const REGISTER_ROWS = 18
+
+// RUN_LIMIT is the number of instructions a single continue may execute before it pauses
+const RUN_LIMIT = 1000000
+
+// ANSI escape sequences used to draw the screen in place
+const (
+	ENTER_SCREEN = "\x1b[?1049h\x1b[?25l"
+	LEAVE_SCREEN = "\x1b[?25h\x1b[?1049l"
+	HOME         = "\x1b[H"
+	CLEAR_LINE   = "\x1b[K"
+)
+
+// Keys that are sent as escape sequences
+const (
+	KEY_UP        = "up"
+	KEY_DOWN      = "down"
+	KEY_PAGE_UP   = "page up"
+	KEY_PAGE_DOWN = "page down"
+	KEY_ESCAPE    = "\x1b"
+)
+
+// HELP lists the keybindings, and is shown when there is no other message
+const HELP = "s step  c continue  b breakpoint  up/down move  . current line  g go to label  n/p memory  q quit"
+
+var (
+	headerStyle     = color.New(color.Bold)
+	currentStyle    = color.New(color.ReverseVideo)
+	cursorStyle     = color.New(color.Underline)
+	changedStyle    = color.New(color.FgGreen, color.Bold)
+	breakpointStyle = color.New(color.FgRed, color.Bold)
+)
+
+// Debugger is a full-screen interface to step through the loaded program.
+// The cursor selects the line that breakpoints are set on, and follows the PC after every step.
+type Debugger struct {
+	monitor     *Limits.Monitor
+	watcher     *Watch.Watcher
+	input       *bufio.Reader
+	breakpoints map[int64]bool
+	labels      map[int64]string
+	previous    [32]int64
+	cursor      int64
+	top         int64
+	memoryStart int64
+	memoryRows  int64
+	steps       int64
+	isStopped   bool
+	status      string
+	message     string
+}
+
+// New is a function to create a debugger for the loaded program. It pauses when the monitor
+// reaches a limit or when a watchpoint breaks. The watcher may be nil.
+func New(monitor *Limits.Monitor, watcher *Watch.Watcher) *Debugger {
+	debugger := Debugger{
+		monitor:     monitor,
+		watcher:     watcher,
+		input:       bufio.NewReader(os.Stdin),
+		breakpoints: make(map[int64]bool),
+		labels:      make(map[int64]string),
+		cursor:      Memory.InstructionMem.PC,
+		status:      "ready",
+	}
+	for label, PC := range Memory.InstructionMem.Labels {
+		if current, isKnown := debugger.labels[PC]; !isKnown || label < current {
+			debugger.labels[PC] = label
+		}
+	}
+	debugger.saveRegisters()
+	if !Memory.IsValidPC(Memory.InstructionMem.PC) {
+		debugger.stop("finished")
+	}
+	return &debugger
+}
+
+// Run is a method to take over the terminal and handle keys until the user quits.
+func (debugger *Debugger) Run() error {
+	restore, err := startRawMode()
+	if err != nil {
+		return err
+	}
+	leave := func() {
+		fmt.Print(LEAVE_SCREEN)
+		restore()
+	}
+	// Ctrl-C still stops ARMed, so the terminal is put back first
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		leave()
+		os.Exit(130)
+	}()
+	defer signal.Stop(interrupt)
+	defer leave()
+
+	fmt.Print(ENTER_SCREEN)
+	for {
+		debugger.draw("")
+		key, err := debugger.readKey()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		debugger.message = ""
+
+		switch key {
+		case "q":
+			return nil
+		case "s", " ":
+			debugger.step()
+		case "c":
+			debugger.run()
+		case "b":
+			debugger.breakpoints[debugger.cursor] = !debugger.breakpoints[debugger.cursor]
+		case KEY_UP, "k":
+			debugger.moveCursor(debugger.cursor - 1)
+		case KEY_DOWN, "j":
+			debugger.moveCursor(debugger.cursor + 1)
+		case ".":
+			debugger.moveCursor(Memory.InstructionMem.PC)
+		case "g":
+			debugger.jumpToLabel()
+		case "n", KEY_PAGE_DOWN:
+			debugger.scrollMemory(2 * debugger.memoryRows)
+		case "p", KEY_PAGE_UP:
+			debugger.scrollMemory(-2 * debugger.memoryRows)
+		}
+	}
+}
+
+// Method to read a key, turning the escape sequences of arrow and page keys into their names.
+func (debugger *Debugger) readKey() (string, error) {
+	key, err := debugger.input.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	// a lone escape is the escape key, a sequence arrives all at once
+	if key != 0x1b || debugger.input.Buffered() < 2 {
+		return string(key), nil
+	}
+	sequence := make([]byte, 2)
+	io.ReadFull(debugger.input, sequence)
+	switch string(sequence) {
+	case "[A", "OA":
+		return KEY_UP, nil
+	case "[B", "OB":
+		return KEY_DOWN, nil
+	case "[5":
+		debugger.input.ReadByte()
+		return KEY_PAGE_UP, nil
+	case "[6":
+		debugger.input.ReadByte()
+		return KEY_PAGE_DOWN, nil
+	}
+	return "", nil
+}
+
+// Method to remember the registers before an instruction, to show which ones change.
+func (debugger *Debugger) saveRegisters() {
+	for i := range debugger.previous {
+		debugger.previous[i] = Memory.GetRegisterValue(uint(i))
+	}
+}
+
+// Method to stop the program for good, e.g. when it ends or fails.
+func (debugger *Debugger) stop(status string) {
+	debugger.isStopped = true
+	debugger.status = status
+}
+
+// Method to execute a single instruction.
+// Returns false if the program stopped or paused and the user has to decide what to do next.
+func (debugger *Debugger) execute() bool {
+	location := Memory.InstructionMem.Locations[Memory.InstructionMem.PC]
+	err := Memory.InstructionMem.ValidateAndExecuteInstruction()
+	debugger.steps++
+	switch {
+	case err != nil:
+		debugger.stop("stopped")
+		debugger.message = location.String() + ": " + err.Error()
+	case !Memory.IsValidPC(Memory.InstructionMem.PC):
+		debugger.stop("finished")
+	case debugger.monitor.Violation() != nil:
+		debugger.stop("stopped")
+		debugger.message = "Error : " + debugger.monitor.Violation().Message
+		if where := debugger.monitor.Where(); where != "" {
+			debugger.message += ", last loop " + where
+		}
+	case debugger.watcher != nil && debugger.watcher.ShouldBreak():
+		debugger.message = "Stopped at a watchpoint"
+	default:
+		return true
+	}
+	return false
+}
+
+// Method to execute the next instruction.
+func (debugger *Debugger) step() {
+	if debugger.isStopped {
+		debugger.message = "The program has " + debugger.status
+		return
+	}
+	debugger.saveRegisters()
+	debugger.execute()
+	debugger.moveCursor(Memory.InstructionMem.PC)
+}
+
+// Method to run until the program ends or reaches a breakpoint.
+func (debugger *Debugger) run() {
+	if debugger.isStopped {
+		debugger.message = "The program has " + debugger.status
+		return
+	}
+	debugger.saveRegisters()
+	for i := 1; debugger.execute(); i++ {
+		if debugger.breakpoints[Memory.InstructionMem.PC] {
+			debugger.message = "Breakpoint at " + Memory.InstructionMem.Locations[Memory.InstructionMem.PC].String()
+			break
+		}
+		if i == RUN_LIMIT {
+			debugger.message = "Paused after " + strconv.Itoa(RUN_LIMIT) + " instructions"
+			break
+		}
+	}
+	debugger.moveCursor(Memory.InstructionMem.PC)
+}
+
+// Method to move the cursor to an instruction, if there is one.
+func (debugger *Debugger) moveCursor(PC int64) {
+	if Memory.IsValidPC(PC) {
+		debugger.cursor = PC
+	}
+}
+
+// Method to scroll the memory view by a number of words.
+func (debugger *Debugger) scrollMemory(words int64) {
+	debugger.memoryStart += words
+	if debugger.memoryStart >= Memory.MEMORY_SIZE {
+		debugger.memoryStart -= words
+	}
+	if debugger.memoryStart < 0 {
+		debugger.memoryStart = 0
+	}
+}
+
+// Method to ask for a label and move the cursor to it.
+func (debugger *Debugger) jumpToLabel() {
+	label := ""
+	for {
+		debugger.draw("Go to label : " + label)
+		key, err := debugger.readKey()
+		if err != nil {
+			return
+		}
+		switch {
+		case key == "\n" || key == "\r":
+			PC, isKnown := Memory.InstructionMem.Labels[label]
+			if !isKnown {
+				debugger.message = "Unknown label " + label
+				return
+			}
+			debugger.moveCursor(PC)
+			return
+		case key == KEY_ESCAPE:
+			return
+		case key == "\x7f" || key == "\b":
+			if len(label) != 0 {
+				label = label[:len(label)-1]
+			}
+		case len(key) == 1 && key[0] > ' ' && key[0] < 0x7f:
+			label += key
+		}
+	}
+}
+
+// Function to cut or pad text with spaces to exactly width characters.
+func fit(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if len(text) > width {
+		return text[:width]
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
+
+// Method to draw the whole screen over the previous one.
+// The last line shows the prompt if there is one, otherwise the last message or the keybindings.
+func (debugger *Debugger) draw(prompt string) {
+	rows, columns := terminalSize()
+	height := rows - 2
+	if height < REGISTER_ROWS {
+		height = REGISTER_ROWS
+	}
+	leftWidth := columns - RIGHT_WIDTH - 3
+	source := debugger.sourcePane(leftWidth, height)
+	state := debugger.statePane(height)
+
+	var screen strings.Builder
+	screen.WriteString(HOME)
+	title := fmt.Sprintf("ARMed  PC %d  %d instructions executed  %s", Memory.InstructionMem.PC, debugger.steps, debugger.status)
+	screen.WriteString(headerStyle.Sprint(fit(title, columns)) + CLEAR_LINE + "\n")
+	for i := 0; i < height; i++ {
+		screen.WriteString(source[i] + " | " + state[i] + CLEAR_LINE + "\n")
+	}
+
+	footer := prompt
+	if footer == "" {
+		footer = debugger.message
+	}
+	if footer == "" {
+		footer = HELP
+	}
+	screen.WriteString(fit(footer, columns-1) + CLEAR_LINE)
+	fmt.Print(screen.String())
+}
+
+// Method to list the instructions around the cursor, one per row, with the current instruction highlighted.
+// A * marks a breakpoint and a > the current instruction.
+func (debugger *Debugger) sourcePane(width int, height int) []string {
+	lines := []string{headerStyle.Sprint(fit("Source", width))}
+	shown := int64(height - 1)
+	if debugger.cursor < debugger.top {
+		debugger.top = debugger.cursor
+	}
+	if debugger.cursor >= debugger.top+shown {
+		debugger.top = debugger.cursor - shown + 1
+	}
+
+	for PC := debugger.top; PC < debugger.top+shown; PC++ {
+		if !Memory.IsValidPC(PC) {
+			lines = append(lines, fit("", width))
+			continue
+		}
+		marker := " "
+		if debugger.breakpoints[PC] {
+			marker = breakpointStyle.Sprint("*")
+		}
+		current := " "
+		if PC == Memory.InstructionMem.PC {
+			current = ">"
+		}
+		label := ""
+		if name, isLabel := debugger.labels[PC]; isLabel {
+			label = name + ":"
+		}
+		text := fmt.Sprintf("%s%4d %-8s %-24s %s", current, PC, label, Memory.InstructionMem.Describe(PC), Memory.InstructionMem.Locations[PC].String())
+		line := fit(text, width-1)
+		switch {
+		case PC == Memory.InstructionMem.PC:
+			line = currentStyle.Sprint(line)
+		case PC == debugger.cursor:
+			line = cursorStyle.Sprint(line)
+		}
+		lines = append(lines, marker+line)
+	}
+	return lines
+}
+
+// Method to list the registers, flags, the stack from SP upwards and a page of data memory.
+// Registers changed by the last step or continue are highlighted.
+func (debugger *Debugger) statePane(height int) []string {
+	lines := []string{headerStyle.Sprint(fit("Registers", RIGHT_WIDTH))}
+	register := func(index int) string {
+		value := Memory.GetRegisterValue(uint(index))
+		text := fmt.Sprintf("R%-3d%17d", index, value)
+		if value != debugger.previous[index] {
+			return changedStyle.Sprint(text)
+		}
+		return text
+	}
+	for i := 0; i < 16; i++ {
+		lines = append(lines, register(i)+"  "+register(i+16))
+	}
+	flags := Memory.GetFlags()
+	text := "Flags"
+	for i, name := range []string{"N", "Z", "V", "C"} {
+		value := 0
+		if flags[i] {
+			value = 1
+		}
+		text += "  " + name + "=" + strconv.Itoa(value)
+	}
+	lines = append(lines, fit(text, RIGHT_WIDTH))
+
+	// the rest is shared by the stack and memory, each with a header
+	remaining := int64(height - REGISTER_ROWS - 2)
+	stackRows := remaining / 2
+	debugger.memoryRows = remaining - stackRows
+
+	SP := Memory.GetRegisterValue(Memory.SP)
+	FP := Memory.GetRegisterValue(29)
+	lines = append(lines, headerStyle.Sprint(fit("Stack (SP = "+strconv.FormatInt(SP, 10)+")", RIGHT_WIDTH)))
+	for i := int64(0); i < stackRows; i++ {
+		address := SP + i*Memory.WORD_SIZE
+		if address < 0 || address/Memory.WORD_SIZE >= Memory.MEMORY_SIZE {
+			lines = append(lines, fit("", RIGHT_WIDTH))
+			continue
+		}
+		text := fmt.Sprintf("SP+%-5d %8d %12d", i*Memory.WORD_SIZE, address, Memory.GetMemoryWord(address/Memory.WORD_SIZE))
+		if address == FP {
+			text += "  <- FP"
+		}
+		lines = append(lines, fit(text, RIGHT_WIDTH))
+	}
+
+	lines = append(lines, headerStyle.Sprint(fit("Memory (n/p to scroll)", RIGHT_WIDTH)))
+	for i := int64(0); i < debugger.memoryRows; i++ {
+		index := debugger.memoryStart + 2*i
+		if index >= Memory.MEMORY_SIZE {
+			lines = append(lines, fit("", RIGHT_WIDTH))
+			continue
+		}
+		text := fmt.Sprintf("%8d %12d", index*Memory.WORD_SIZE, Memory.GetMemoryWord(index))
+		if index+1 < Memory.MEMORY_SIZE {
+			text += fmt.Sprintf(" %12d", Memory.GetMemoryWord(index+1))
+		}
+		lines = append(lines, fit(text, RIGHT_WIDTH))
+	}
+
+	for len(lines) < height {
+		lines = append(lines, fit("", RIGHT_WIDTH))
+	}
+	return lines[:height]
+}
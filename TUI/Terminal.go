@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+package tui
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Function to run stty on the terminal and return what it prints.
+func stty(args ...string) (string, error) {
+	command := exec.Command("stty", args...)
+	command.Stdin = os.Stdin
+	output, err := command.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// Function to read keys as soon as they are pressed, without echoing them.
+// Returns a function that puts the terminal back the way it was.
+func startRawMode() (func(), error) {
+	settings, err := stty("-g")
+	if err != nil {
+		return nil, errors.New("Error : The full-screen interface needs a terminal")
+	}
+	_, err = stty("-icanon", "-echo", "min", "1")
+	if err != nil {
+		return nil, errors.New("Error setting up the terminal : " + err.Error())
+	}
+	return func() { stty(settings) }, nil
+}
+
+// Function to find the number of rows and columns of the terminal.
+func terminalSize() (int, int) {
+	size, err := stty("size")
+	fields := strings.Fields(size)
+	if err != nil || len(fields) != 2 {
+		return DEFAULT_ROWS, DEFAULT_COLUMNS
+	}
+	rows, err1 := strconv.Atoi(fields[0])
+	columns, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows == 0 || columns == 0 {
+		return DEFAULT_ROWS, DEFAULT_COLUMNS
+	}
+	return rows, columns
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package tui
+
+import (
+	"errors"
+)
+
+// Function to read keys as soon as they are pressed. Not supported on Windows.
+func startRawMode() (func(), error) {
+	return nil, errors.New("Error : The full-screen interface is not supported on Windows")
+}
+
+// Function to find the number of rows and columns of the terminal.
+func terminalSize() (int, int) {
+	return DEFAULT_ROWS, DEFAULT_COLUMNS
+}
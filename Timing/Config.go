@@ -0,0 +1,109 @@
+package timing
+
+import (
+	"bufio"
+	"errors"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config holds the cycle cost of every instruction class, costs of single mnemonics
+// that override the cost of their class, and the clock rate in Hz.
+type Config struct {
+	ClassCosts    map[string]int64
+	MnemonicCosts map[string]int64
+	ClockRate     float64
+}
+
+// DefaultConfig is a function to return a configuration where every instruction takes one cycle.
+func DefaultConfig() Config {
+	config := Config{ClassCosts: make(map[string]int64), MnemonicCosts: make(map[string]int64)}
+	for _, class := range Memory.Classes {
+		config.ClassCosts[class] = 1
+	}
+	return config
+}
+
+// ReadConfig is a function to read a cost file. Each line is "name = value", where name is
+// an instruction class, a mnemonic or clock. Anything after '#' is a comment.
+//
+//	clock = 2 GHz
+//	load = 5
+//	branch taken = 3
+//	MUL = 10
+func ReadConfig(fileName string) (Config, error) {
+	config := DefaultConfig()
+	file, err := os.Open(fileName)
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if line == "" {
+			continue
+		}
+		location := fileName + ":" + strconv.Itoa(lineNumber) + ": "
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return config, errors.New(location + "Expected name = value, found " + line)
+		}
+		name, value := strings.Join(strings.Fields(parts[0]), " "), strings.TrimSpace(parts[1])
+
+		if strings.ToLower(name) == "clock" {
+			config.ClockRate, err = ParseClockRate(value)
+			if err != nil {
+				return config, errors.New(location + err.Error())
+			}
+			continue
+		}
+
+		cost, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || cost < 0 {
+			return config, errors.New(location + "Invalid cycle count " + value)
+		}
+		if class := findClass(name); class != "" {
+			config.ClassCosts[class] = cost
+		} else if strings.Contains(name, " ") {
+			return config, errors.New(location + "Unknown instruction class " + name)
+		} else {
+			config.MnemonicCosts[strings.ToUpper(name)] = cost
+		}
+	}
+	return config, scanner.Err()
+}
+
+// Function to find the instruction class with the given name, in any case.
+// Returns an empty string if there is none.
+func findClass(name string) string {
+	for _, class := range Memory.Classes {
+		if strings.EqualFold(class, name) {
+			return class
+		}
+	}
+	return ""
+}
+
+// ParseClockRate is a function to read a clock rate such as 500 MHz or 2GHz, and return it in Hz.
+func ParseClockRate(value string) (float64, error) {
+	value = strings.ToLower(strings.Replace(value, " ", "", -1))
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix     string
+		multiplier float64
+	}{{"ghz", 1e9}, {"mhz", 1e6}, {"khz", 1e3}, {"hz", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSuffix(value, unit.suffix), unit.multiplier
+			break
+		}
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate <= 0 {
+		return 0, errors.New("Invalid clock rate " + value)
+	}
+	return rate * multiplier, nil
+}
@@ -0,0 +1,105 @@
+package timing
+
+import (
+	"fmt"
+	Memory "github.com/coderick14/ARMed/Memory"
+	tablewriter "github.com/olekukonko/tablewriter"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Model is a simple timing model in which every instruction takes a fixed number of cycles,
+// given by its mnemonic if the configuration has a cost for it and by its class otherwise.
+type Model struct {
+	config       Config
+	instructions int64
+	cycles       int64
+	counts       map[string]int64
+}
+
+// NewModel is a function to create a timing model from a configuration.
+func NewModel(config Config) *Model {
+	return &Model{config: config, counts: make(map[string]int64)}
+}
+
+// Method to find what an executed instruction is charged as, a mnemonic or a class.
+func (model *Model) category(execution *Memory.Execution) string {
+	if _, hasCost := model.config.MnemonicCosts[execution.Mnemonic]; hasCost {
+		return execution.Mnemonic
+	}
+	return execution.Class()
+}
+
+// Method to return the cost of a mnemonic or class.
+func (model *Model) cost(category string) int64 {
+	if cost, hasCost := model.config.MnemonicCosts[category]; hasCost {
+		return cost
+	}
+	return model.config.ClassCosts[category]
+}
+
+// Cost is a method to find the number of cycles an executed instruction takes.
+func (model *Model) Cost(execution *Memory.Execution) int64 {
+	return model.cost(model.category(execution))
+}
+
+// Observe is a method to add the cost of an executed instruction.
+func (model *Model) Observe(execution *Memory.Execution) {
+	category := model.category(execution)
+	model.counts[category]++
+	model.instructions++
+	model.cycles += model.cost(category)
+}
+
+// Function to format a time in seconds with a unit that suits it.
+func formatTime(seconds float64) string {
+	switch {
+	case seconds < 1e-6:
+		return strconv.FormatFloat(seconds*1e9, 'f', 3, 64) + " ns"
+	case seconds < 1e-3:
+		return strconv.FormatFloat(seconds*1e6, 'f', 3, 64) + " us"
+	case seconds < 1:
+		return strconv.FormatFloat(seconds*1e3, 'f', 3, 64) + " ms"
+	}
+	return strconv.FormatFloat(seconds, 'f', 3, 64) + " s"
+}
+
+// ShowSummary is a method to pretty print the cycles spent on each class and mnemonic,
+// total cycles, CPI and, if the clock rate is known, the execution time.
+func (model *Model) ShowSummary() {
+	var mnemonics []string
+	for mnemonic := range model.config.MnemonicCosts {
+		mnemonics = append(mnemonics, mnemonic)
+	}
+	sort.Strings(mnemonics)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Class or mnemonic", "Instructions", "Cycles each", "Cycles"})
+	for _, category := range append(append([]string{}, Memory.Classes...), mnemonics...) {
+		count := model.counts[category]
+		table.Append([]string{category, strconv.FormatInt(count, 10), strconv.FormatInt(model.cost(category), 10), strconv.FormatInt(count*model.cost(category), 10)})
+	}
+	table.Render()
+	fmt.Printf("\n")
+
+	cpi, time := "-", "-"
+	if model.instructions != 0 {
+		cpi = strconv.FormatFloat(float64(model.cycles)/float64(model.instructions), 'f', 2, 64)
+	}
+	if model.config.ClockRate != 0 {
+		time = formatTime(float64(model.cycles) / model.config.ClockRate)
+	}
+
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Timing", "Value"})
+	table.Append([]string{"Instructions", strconv.FormatInt(model.instructions, 10)})
+	table.Append([]string{"Cycles", strconv.FormatInt(model.cycles, 10)})
+	table.Append([]string{"CPI", cpi})
+	if model.config.ClockRate != 0 {
+		table.Append([]string{"Clock rate", strconv.FormatFloat(model.config.ClockRate/1e6, 'f', -1, 64) + " MHz"})
+	}
+	table.Append([]string{"Execution time", time})
+	table.Render()
+	fmt.Printf("\n")
+}
@@ -0,0 +1,229 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"strconv"
+	"strings"
+)
+
+// Kinds of access a watchpoint reacts to
+const (
+	WRITE_ACCESS = "write"
+	READ_ACCESS  = "read"
+	ANY_ACCESS   = "access"
+)
+
+// NO_REGISTER marks a watchpoint that does not watch a register
+const NO_REGISTER = -1
+
+// Watchpoint reports accesses to a register or to a range of memory, optionally only when a condition holds.
+// A watchpoint with only a condition reports each time the condition becomes true.
+// An address over registers, such as SP+8, is kept in AddressExpression and found again at every instruction.
+type Watchpoint struct {
+	Spec              string
+	IsBreak           bool
+	Register          int
+	Address           int64
+	AddressExpression string
+	Length            int64
+	Access            string
+	Condition         string
+	wasTrue           bool
+}
+
+// Parse is a function to read a watchpoint specification of the form
+// [break] [TARGET [write|read|access]] [if CONDITION], for example "X9",
+// "break [SP, 8] write" or "break if X9 < 0". TARGET is a register, or a memory
+// address with an optional length in bytes, as in [0x40] or [0x40, 16].
+// CONDITION is an expression over the registers, and the old and new values of the access.
+// Addresses may use the registers, as in [SP, 8] or [FP - 16], but lengths must be constants.
+func Parse(spec string) (*Watchpoint, error) {
+	watchpoint := Watchpoint{Spec: spec, Register: NO_REGISTER, Access: WRITE_ACCESS}
+	words := strings.Fields(spec)
+	for i, word := range words {
+		if word == "if" {
+			watchpoint.Condition = strings.Join(words[i+1:], " ")
+			words = words[:i]
+			break
+		}
+	}
+	if watchpoint.Condition != "" {
+		if _, err := Assembler.Evaluate(watchpoint.Condition, conditionSymbols(0, 0)); err != nil {
+			return nil, errors.New(err.Error() + " in watchpoint " + spec)
+		}
+	}
+	text := strings.Join(words, " ")
+
+	if text == "break" || strings.HasPrefix(text, "break ") {
+		watchpoint.IsBreak = true
+		text = strings.TrimSpace(strings.TrimPrefix(text, "break"))
+	}
+
+	for _, access := range []string{WRITE_ACCESS, READ_ACCESS, ANY_ACCESS} {
+		if strings.HasSuffix(text, " "+access) {
+			watchpoint.Access = access
+			text = strings.TrimSpace(strings.TrimSuffix(text, access))
+		}
+	}
+
+	switch {
+	case text == "":
+		if watchpoint.Condition == "" {
+			return nil, errors.New("Expected a register, an address or a condition in watchpoint " + spec)
+		}
+	case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+		parts := strings.SplitN(text[1:len(text)-1], ",", 2)
+		address, err := Assembler.Evaluate(parts[0], nil)
+		if err != nil {
+			_, err = Assembler.Evaluate(parts[0], conditionSymbols(0, 0))
+			if err != nil {
+				return nil, errors.New(err.Error() + " in watchpoint " + spec)
+			}
+			watchpoint.AddressExpression = strings.TrimSpace(parts[0])
+		}
+		watchpoint.Address, watchpoint.Length = address, Memory.WORD_SIZE
+		if len(parts) == 2 {
+			watchpoint.Length, err = Assembler.Evaluate(parts[1], nil)
+			if err != nil || watchpoint.Length <= 0 {
+				return nil, errors.New("Invalid length in watchpoint " + spec)
+			}
+		}
+	default:
+		register, isRegister := Assembler.RegisterNumber(text)
+		if !isRegister {
+			return nil, errors.New("Expected a register or an address, found " + text + " in watchpoint " + spec)
+		}
+		watchpoint.Register = int(register)
+	}
+	return &watchpoint, nil
+}
+
+// Method to describe what a watchpoint watches.
+func (watchpoint *Watchpoint) target() string {
+	if watchpoint.Register != NO_REGISTER {
+		return "X" + strconv.Itoa(watchpoint.Register)
+	}
+	address := strconv.FormatInt(watchpoint.Address, 10)
+	if watchpoint.AddressExpression != "" {
+		address = watchpoint.AddressExpression
+	}
+	return "[" + address + ", " + strconv.FormatInt(watchpoint.Length, 10) + "]"
+}
+
+// Method to find the address a watchpoint watches during an instruction.
+// Registers are read as the instruction saw them, before its own writes.
+// Returns false if the address cannot be found.
+func (watchpoint *Watchpoint) address(execution *Memory.Execution) (int64, bool) {
+	if watchpoint.AddressExpression == "" {
+		return watchpoint.Address, true
+	}
+	symbols := conditionSymbols(0, 0)
+	for _, write := range execution.RegistersWritten {
+		setRegisterSymbols(symbols, write.Register, write.OldValue)
+	}
+	address, err := Assembler.Evaluate(watchpoint.AddressExpression, symbols)
+	return address, err == nil
+}
+
+// Method to check if a watchpoint reacts to a read or a write.
+func (watchpoint *Watchpoint) isWatching(isWrite bool) bool {
+	return watchpoint.Access == ANY_ACCESS || (watchpoint.Access == WRITE_ACCESS) == isWrite
+}
+
+// Function to build the symbols a condition may use: every register by name, and the old
+// and new value of the access that triggered the watchpoint.
+func conditionSymbols(oldValue int64, newValue int64) map[string]int64 {
+	symbols := map[string]int64{"old": oldValue, "new": newValue}
+	for register := uint(0); register <= Memory.XZR; register++ {
+		setRegisterSymbols(symbols, register, Memory.GetRegisterValue(register))
+	}
+	return symbols
+}
+
+// Function to set the value of a register under its number and its name, such as X28 and SP, in both cases.
+func setRegisterSymbols(symbols map[string]int64, register uint, value int64) {
+	for _, name := range []string{"X" + strconv.Itoa(int(register)), Memory.RegisterName(int(register))} {
+		symbols[name] = value
+		symbols[strings.ToLower(name)] = value
+	}
+}
+
+// Method to check the condition of a watchpoint. A watchpoint without a condition always triggers.
+func (watchpoint *Watchpoint) holds(oldValue int64, newValue int64) bool {
+	if watchpoint.Condition == "" {
+		return true
+	}
+	value, err := Assembler.Evaluate(watchpoint.Condition, conditionSymbols(oldValue, newValue))
+	return err == nil && value != 0
+}
+
+// Watcher checks every watchpoint after each executed instruction.
+type Watcher struct {
+	watchpoints []*Watchpoint
+	isBreaking  bool
+}
+
+// NewWatcher is a function to create a watcher for the given watchpoints.
+func NewWatcher(watchpoints []*Watchpoint) *Watcher {
+	return &Watcher{watchpoints: watchpoints}
+}
+
+// Observe is a method to report the accesses of an instruction that watchpoints are waiting for.
+func (watcher *Watcher) Observe(execution *Memory.Execution) {
+	where := " by " + Memory.InstructionMem.Describe(execution.PC) + " at " + Memory.InstructionMem.Locations[execution.PC].String()
+
+	for i, watchpoint := range watcher.watchpoints {
+		report := func(message string) {
+			fmt.Println("Watchpoint " + strconv.Itoa(i+1) + " : " + message + where)
+			watcher.isBreaking = watcher.isBreaking || watchpoint.IsBreak
+		}
+
+		switch {
+		case watchpoint.Register != NO_REGISTER:
+			register := uint(watchpoint.Register)
+			for _, write := range execution.RegistersWritten {
+				if write.Register == register && watchpoint.isWatching(true) && watchpoint.holds(write.OldValue, write.NewValue) {
+					report(watchpoint.target() + " changed from " + strconv.FormatInt(write.OldValue, 10) + " to " + strconv.FormatInt(write.NewValue, 10))
+				}
+			}
+			for _, read := range execution.RegistersRead {
+				value := Memory.GetRegisterValue(register)
+				if read == register && watchpoint.isWatching(false) && watchpoint.holds(value, value) {
+					report(watchpoint.target() + " read")
+				}
+			}
+
+		case watchpoint.Length != 0:
+			address, isFound := watchpoint.address(execution)
+			for _, access := range execution.MemoryAccesses {
+				overlaps := isFound && access.Address < address+watchpoint.Length && address < access.Address+access.Size
+				if !overlaps || !watchpoint.isWatching(access.IsWrite) {
+					continue
+				}
+				location := "[" + strconv.FormatInt(access.Address, 10) + "]"
+				if access.IsWrite && watchpoint.holds(access.OldValue, access.Value) {
+					report(location + " changed from " + strconv.FormatInt(access.OldValue, 10) + " to " + strconv.FormatInt(access.Value, 10))
+				} else if !access.IsWrite && watchpoint.holds(access.Value, access.Value) {
+					report(location + " read, value " + strconv.FormatInt(access.Value, 10))
+				}
+			}
+
+		default:
+			isTrue := watchpoint.holds(0, 0)
+			if isTrue && !watchpoint.wasTrue {
+				report(watchpoint.Condition + " became true")
+			}
+			watchpoint.wasTrue = isTrue
+		}
+	}
+}
+
+// ShouldBreak is a method to check if a break watchpoint triggered since it was last called.
+func (watcher *Watcher) ShouldBreak() bool {
+	isBreaking := watcher.isBreaking
+	watcher.isBreaking = false
+	return isBreaking
+}
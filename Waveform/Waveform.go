@@ -0,0 +1,260 @@
+package waveform
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Encoder "github.com/coderick14/ARMed/Encoder"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"os"
+	"strconv"
+)
+
+// PERIOD is the length of a clock cycle, in the units of the timescale
+const PERIOD = 10
+
+// TIMESCALE is the unit of time of the dump
+const TIMESCALE = "1ns"
+
+// Struct to hold a variable of the dump, which is written only when its value changes
+type vcdSignal struct {
+	name     string
+	width    int
+	id       string
+	value    uint64
+	isDumped bool
+}
+
+// Writer dumps the state of the machine after every instruction as a Value Change Dump.
+// Each instruction takes one clock cycle, or the cycles given by its cost if there is one. The program counter
+// and the instruction change at the start of its first cycle, the write and memory signals at the start of its
+// last cycle, and the registers and flags on the rising edge of its last cycle, as they would in hardware.
+// The write and memory signals are named as in the textbook datapath, which is what ARMed cosim expects by default,
+// and return addresses are shown as byte addresses.
+type Writer struct {
+	file      *os.File
+	writer    *bufio.Writer
+	encoder   *Encoder.Encoder
+	addresses *Memory.ReturnAddresses
+	cost      func(execution *Memory.Execution) int64
+	signals   map[string]*vcdSignal
+	order     []*vcdSignal
+	cycle     int64
+}
+
+// Names of the signals that are not registers
+const (
+	CLOCK_SIGNAL          = "clk"
+	PC_SIGNAL             = "PC"
+	INSTRUCTION_SIGNAL    = "Instruction"
+	REG_WRITE_SIGNAL      = "RegWrite"
+	WRITE_REGISTER_SIGNAL = "WriteRegister"
+	WRITE_DATA_SIGNAL     = "WriteData"
+	MEM_READ_SIGNAL       = "MemRead"
+	MEM_WRITE_SIGNAL      = "MemWrite"
+	MEM_ADDRESS_SIGNAL    = "MemAddress"
+	MEM_WRITE_DATA_SIGNAL = "MemWriteData"
+	MEM_READ_DATA_SIGNAL  = "MemReadData"
+)
+
+// Flags in the order they are declared
+var flagNames = []string{"N", "Z", "C", "V"}
+
+// New is a function to create a dump of a program in a file, starting with the state the program is loaded in.
+// Cost, if not nil, gives the number of cycles of each executed instruction.
+func New(fileName string, program *Assembler.Object, cost func(execution *Memory.Execution) int64) (*Writer, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, errors.New("Error creating file : " + err.Error())
+	}
+	writer := Writer{
+		file:      file,
+		writer:    bufio.NewWriter(file),
+		encoder:   Encoder.New(program),
+		addresses: Memory.NewReturnAddresses(),
+		cost:      cost,
+		signals:   make(map[string]*vcdSignal),
+	}
+
+	writer.declare(CLOCK_SIGNAL, 1)
+	writer.declare(PC_SIGNAL, 64)
+	writer.declare(INSTRUCTION_SIGNAL, 32)
+	for register := 0; register < Memory.XZR; register++ {
+		writer.declare(Memory.RegisterName(register), 64)
+	}
+	for _, flag := range flagNames {
+		writer.declare(flag, 1)
+	}
+	writer.declare(REG_WRITE_SIGNAL, 1)
+	writer.declare(WRITE_REGISTER_SIGNAL, 5)
+	writer.declare(WRITE_DATA_SIGNAL, 64)
+	writer.declare(MEM_READ_SIGNAL, 1)
+	writer.declare(MEM_WRITE_SIGNAL, 1)
+	writer.declare(MEM_ADDRESS_SIGNAL, 64)
+	writer.declare(MEM_WRITE_DATA_SIGNAL, 64)
+	writer.declare(MEM_READ_DATA_SIGNAL, 64)
+
+	fmt.Fprintf(writer.writer, "$version ARMed $end\n")
+	fmt.Fprintf(writer.writer, "$timescale %s $end\n", TIMESCALE)
+	fmt.Fprintf(writer.writer, "$scope module ARMed $end\n")
+	for _, signal := range writer.order {
+		fmt.Fprintf(writer.writer, "$var wire %d %s %s $end\n", signal.width, signal.id, signal.name)
+	}
+	fmt.Fprintf(writer.writer, "$upscope $end\n")
+	fmt.Fprintf(writer.writer, "$enddefinitions $end\n")
+
+	fmt.Fprintf(writer.writer, "#0\n$dumpvars\n")
+	writer.set(PC_SIGNAL, uint64(Memory.InstructionMem.PC*Memory.WORD_SIZE))
+	writer.setState()
+	for _, signal := range writer.order {
+		if !signal.isDumped {
+			writer.write(signal, 0)
+		}
+	}
+	fmt.Fprintf(writer.writer, "$end\n")
+	return &writer, nil
+}
+
+// Method to declare a signal, with the next unused identifier.
+// Identifiers are written with the printable characters from ! to ~.
+func (writer *Writer) declare(name string, width int) {
+	id := ""
+	for index := len(writer.order); ; index = index/94 - 1 {
+		id = string(rune('!'+index%94)) + id
+		if index < 94 {
+			break
+		}
+	}
+	signal := vcdSignal{name: name, width: width, id: id}
+	writer.signals[name] = &signal
+	writer.order = append(writer.order, &signal)
+}
+
+// Method to write a value of a signal.
+func (writer *Writer) write(signal *vcdSignal, value uint64) {
+	if signal.width == 1 {
+		fmt.Fprintf(writer.writer, "%d%s\n", value&1, signal.id)
+	} else {
+		fmt.Fprintf(writer.writer, "b%s %s\n", strconv.FormatUint(value, 2), signal.id)
+	}
+	signal.value, signal.isDumped = value, true
+}
+
+// Method to change a signal, which is written only if its value is new.
+func (writer *Writer) set(name string, value uint64) {
+	signal := writer.signals[name]
+	if signal.width < 64 {
+		value &= uint64(1)<<uint(signal.width) - 1
+	}
+	if !signal.isDumped || signal.value != value {
+		writer.write(signal, value)
+	}
+}
+
+// Method to set the registers and flags to the state of the machine.
+func (writer *Writer) setState() {
+	for register := 0; register < Memory.XZR; register++ {
+		value := uint64(Memory.GetRegisterValue(uint(register)))
+		writer.set(Memory.RegisterName(register), writer.addresses.RegisterInHardware(uint(register), value))
+	}
+	flags := Memory.GetFlags()
+	// GetFlags returns N, Z, V and C
+	for i, isSet := range []bool{flags[0], flags[1], flags[3], flags[2]} {
+		value := uint64(0)
+		if isSet {
+			value = 1
+		}
+		writer.set(flagNames[i], value)
+	}
+}
+
+// Method to start a time step.
+func (writer *Writer) at(time int64) {
+	fmt.Fprintf(writer.writer, "#%d\n", time)
+}
+
+// Observe is a method to dump the cycles of an executed instruction.
+func (writer *Writer) Observe(execution *Memory.Execution) {
+	writer.addresses.Observe(execution)
+	cycles := int64(1)
+	if writer.cost != nil && writer.cost(execution) > 1 {
+		cycles = writer.cost(execution)
+	}
+
+	for i := int64(1); i <= cycles; i++ {
+		// the first cycle is one period after the dump starts, so the clock is low at the start of every cycle
+		writer.cycle++
+		writer.at(writer.cycle * PERIOD)
+		writer.set(CLOCK_SIGNAL, 0)
+		if i == 1 {
+			writer.set(PC_SIGNAL, uint64(execution.PC*Memory.WORD_SIZE))
+			instruction, err := writer.encoder.Encode(execution.Instruction, execution.PC)
+			if err == nil {
+				writer.set(INSTRUCTION_SIGNAL, uint64(instruction))
+			}
+		}
+		if i == cycles {
+			writer.setBus(execution)
+		} else {
+			writer.set(REG_WRITE_SIGNAL, 0)
+			writer.set(MEM_READ_SIGNAL, 0)
+			writer.set(MEM_WRITE_SIGNAL, 0)
+		}
+
+		writer.at(writer.cycle*PERIOD + PERIOD/2)
+		writer.set(CLOCK_SIGNAL, 1)
+		if i == cycles {
+			writer.setState()
+		}
+	}
+}
+
+// Method to set the write and memory signals of an instruction.
+// Writes to XZR are discarded by ARMed, so they do not assert RegWrite.
+func (writer *Writer) setBus(execution *Memory.Execution) {
+	regWrite, memRead, memWrite := uint64(0), uint64(0), uint64(0)
+	for _, write := range execution.RegistersWritten {
+		regWrite = 1
+		writer.set(WRITE_REGISTER_SIGNAL, uint64(write.Register))
+		writer.set(WRITE_DATA_SIGNAL, writer.addresses.RegisterInHardware(write.Register, uint64(write.NewValue)))
+	}
+	for _, access := range execution.MemoryAccesses {
+		value := writer.addresses.WordInHardware(access.Address, uint64(access.Value))
+		writer.set(MEM_ADDRESS_SIGNAL, uint64(access.Address))
+		if access.IsWrite {
+			memWrite = 1
+			writer.set(MEM_WRITE_DATA_SIGNAL, value)
+		} else {
+			memRead = 1
+			writer.set(MEM_READ_DATA_SIGNAL, value)
+		}
+	}
+	writer.set(REG_WRITE_SIGNAL, regWrite)
+	writer.set(MEM_READ_SIGNAL, memRead)
+	writer.set(MEM_WRITE_SIGNAL, memWrite)
+}
+
+// Close is a method to end the dump one cycle after the last instruction and close the file.
+// It may be called more than once.
+func (writer *Writer) Close() error {
+	if writer.file == nil {
+		return nil
+	}
+	writer.cycle++
+	writer.at(writer.cycle * PERIOD)
+	writer.set(CLOCK_SIGNAL, 0)
+	writer.set(REG_WRITE_SIGNAL, 0)
+	writer.set(MEM_READ_SIGNAL, 0)
+	writer.set(MEM_WRITE_SIGNAL, 0)
+
+	err := writer.writer.Flush()
+	if closeErr := writer.file.Close(); err == nil {
+		err = closeErr
+	}
+	writer.file = nil
+	if err != nil {
+		return errors.New("Error writing file : " + err.Error())
+	}
+	return nil
+}
@@ -4,7 +4,19 @@ ARMed version 1.0
 Author : https://github.com/coderick14
 
 	ARMed is a very basic emulator of the ARM instruction set written in Golang
-	USAGE : ARMed [OPTIONS]... SOURCE_FILE
+	USAGE : ARMed [OPTIONS]... FILE...
+	        ARMed assemble [-o OBJECT_FILE] SOURCE_FILE
+	        ARMed link [-o OUTPUT_FILE] FILE...
+	        ARMed test SPEC_FILE
+	        ARMed export [-format FORMAT] [-text FILE] [-data FILE] FILE...
+	        ARMed cosim [-signals SPEC] [-max-instructions N] TRACE_FILE FILE...
+
+	Each FILE is a source file or an object file written by assemble or link.
+	Files are linked together. Execution starts at the first instruction of the first file
+	and ends when it runs past the last instruction of that file.
+	test runs the cases of a YAML spec and checks their final registers, memory and exit code.
+	export writes the LEGv8 machine code and initial data memory as readmemh, readmemb, logisim or mif images.
+	cosim checks the register and memory writes of a hardware simulation, from a text or VCD trace, against ARMed.
 
 	Example SOURCE_FILE :
 
@@ -32,6 +44,38 @@ Author : https://github.com/coderick14
 	--all 		show all register values after an instruction, with updated ones in color
 	--end 		show updated registers only once, at the end of the program. Overrides --all
 	--no-log 	suppress logs of statements being executed
+	--check 	check the program for errors and warnings without running it
+	--pipeline 	show how the program runs on the five-stage pipeline, with total cycles and CPI
+	--forwarding 	forward results between pipeline stages instead of waiting for write back. Used with --pipeline
+	--cache SPEC 	add a level to the data cache and show its hits and misses. Repeat for L2, L3 ...
+	--icache SPEC 	add a level to the instruction cache
+			SPEC is a list like size=1024,block=16,ways=2,replace=lru,write=back,allocate=yes
+	--predictor SPEC 	run a branch predictor on B.cond, CBZ and CBNZ and show its accuracy. Repeat to compare
+			SPEC is taken, not-taken, 1bit, 2bit or gshare, optionally followed by entries=N, history=N and btb=N
+	--stats FORMAT 	show how many instructions of each kind ran, bytes of memory used and stack depth. FORMAT is table or json
+	--costs FILE 	show total cycles and CPI, with the cycles of each instruction class read from FILE
+	--clock RATE 	clock rate such as 2GHz, to also show the execution time. Used with --costs
+	--profile 	show the instructions executed by each function and the most executed source lines
+	--folded FILE 	write the call stacks of the profile to FILE, for flame graph tools. Used with --profile
+	--watch SPEC 	report writes to a register or memory. Repeat for more watchpoints. SPEC is [break] TARGET [write|read|access] [if CONDITION]
+			e.g. X9, "[0x40, 16] read", "break SP if new < 16000" or "break if X9 < 0"
+	--max-instructions N 	stop after N instructions, with exit status 3
+	--max-time DURATION 	stop after running for DURATION such as 10s, with exit status 4
+	--max-memory BYTES 	stop once more than BYTES of data memory are touched, with exit status 5
+	--max-output BYTES 	stop once more than BYTES of "Executing :" logs are printed, with exit status 6. Has no effect with --no-log
+	--detect-loops 	stop a loop that repeats with no change to registers, flags or memory, with exit status 7
+	--serve ADDRESS 	step through the program in a web browser, served on ADDRESS such as :8080, which is local only.
+			Use 0.0.0.0:8080 to serve on every interface
+	--tui 		step through the program in a full-screen view of the source, registers, flags, stack and memory
+	--reg REGISTER=VALUE 	set a register before the program starts, e.g. X0=5 or X1=0x10. Repeat for more registers
+	--mem ADDRESS=WORDS 	set words of data memory from a byte address before the program starts, e.g. 0x100=1,2,3
+	--mem-file FILE@ADDRESS 	copy the bytes of FILE into data memory from a byte address, e.g. data.bin@0x200
+	--load-memory FILE 	load data memory from an Intel HEX (.hex) or S-record (.srec, .s19) file before the program starts
+	--dump-memory FILE 	write the non-zero words of data memory to an Intel HEX or S-record file once the program ends
+	--vcd FILE 	write the PC, registers, flags and memory bus signals of every instruction to FILE as a Value Change Dump
+			for waveform viewers such as GTKWave. With --costs, each instruction lasts its cycles
+	--output FORMAT 	run to the end and print the registers, flags, PC, exit reason, instruction count and memory
+			as json or yaml instead of tables. With --all, also print them after every instruction
 	--help 		display help
 
 Found a bug? Feel free to raise an issue on https://github.com/coderick14/ARMed
@@ -41,39 +85,141 @@ Contributions welcome :)
 package main
 
 import (
-	"bufio"
 	"errors"
 	"flag"
 	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	HexFile "github.com/coderick14/ARMed/HexFile"
+	Limits "github.com/coderick14/ARMed/Limits"
 	Memory "github.com/coderick14/ARMed/Memory"
-	"io"
+	Output "github.com/coderick14/ARMed/Output"
+	Pipeline "github.com/coderick14/ARMed/Pipeline"
+	Profiler "github.com/coderick14/ARMed/Profiler"
+	Server "github.com/coderick14/ARMed/Server"
+	Statistics "github.com/coderick14/ARMed/Statistics"
+	TUI "github.com/coderick14/ARMed/TUI"
+	Watch "github.com/coderick14/ARMed/Watch"
+	Waveform "github.com/coderick14/ARMed/Waveform"
 	"os"
-	"strings"
 )
 
 const helpString = `ARMed version 1.0
 Author : https://github.com/coderick14
 
 ARMed is a very basic emulator of the ARM instruction set written in Golang
-USAGE : ARMed [OPTIONS]... SOURCE_FILE
+USAGE : ARMed [OPTIONS]... FILE...
+        ARMed assemble [-o OBJECT_FILE] SOURCE_FILE
+        ARMed link [-o OUTPUT_FILE] FILE...
+        ARMed test SPEC_FILE
+        ARMed export [-format FORMAT] [-text FILE] [-data FILE] FILE...
+        ARMed cosim [-signals SPEC] [-max-instructions N] TRACE_FILE FILE...
+
+Each FILE is a source file or an object file written by assemble or link.
+Files are linked together. Execution starts at the first instruction of the first file
+and ends when it runs past the last instruction of that file.
+test runs the cases of a YAML spec and checks their final registers, memory and exit code.
+export writes the LEGv8 machine code and initial data memory as readmemh, readmemb, logisim or mif images.
+cosim checks the register and memory writes of a hardware simulation, from a text or VCD trace, against ARMed.
 
 --all 		show all register values after an instruction, with updated ones in color
 --end 		show updated registers only once, at the end of the program. Overrides --all
 --no-log 	suppress logs of statements being executed
+--check 	check the program for errors and warnings without running it
+--pipeline 	show how the program runs on the five-stage pipeline, with total cycles and CPI
+--forwarding 	forward results between pipeline stages instead of waiting for write back. Used with --pipeline
+--cache SPEC 	add a level to the data cache and show its hits and misses. Repeat for L2, L3 ...
+--icache SPEC 	add a level to the instruction cache
+		SPEC is a list like size=1024,block=16,ways=2,replace=lru,write=back,allocate=yes
+--predictor SPEC 	run a branch predictor on B.cond, CBZ and CBNZ and show its accuracy. Repeat to compare
+		SPEC is taken, not-taken, 1bit, 2bit or gshare, optionally followed by entries=N, history=N and btb=N
+--stats FORMAT 	show how many instructions of each kind ran, bytes of memory used and stack depth. FORMAT is table or json
+--costs FILE 	show total cycles and CPI, with the cycles of each instruction class read from FILE
+--clock RATE 	clock rate such as 2GHz, to also show the execution time. Used with --costs
+--profile 	show the instructions executed by each function and the most executed source lines
+--folded FILE 	write the call stacks of the profile to FILE, for flame graph tools. Used with --profile
+--watch SPEC 	report writes to a register or memory. Repeat for more watchpoints. SPEC is [break] TARGET [write|read|access] [if CONDITION]
+		e.g. X9, "[0x40, 16] read", "break SP if new < 16000" or "break if X9 < 0"
+--max-instructions N 	stop after N instructions, with exit status 3
+--max-time DURATION 	stop after running for DURATION such as 10s, with exit status 4
+--max-memory BYTES 	stop once more than BYTES of data memory are touched, with exit status 5
+--max-output BYTES 	stop once more than BYTES of "Executing :" logs are printed, with exit status 6. Has no effect with --no-log
+--detect-loops 	stop a loop that repeats with no change to registers, flags or memory, with exit status 7
+--serve ADDRESS 	step through the program in a web browser, served on ADDRESS such as :8080, which is local only.
+		Use 0.0.0.0:8080 to serve on every interface
+--tui 		step through the program in a full-screen view of the source, registers, flags, stack and memory
+--reg REGISTER=VALUE 	set a register before the program starts, e.g. X0=5 or X1=0x10. Repeat for more registers
+--mem ADDRESS=WORDS 	set words of data memory from a byte address before the program starts, e.g. 0x100=1,2,3
+--mem-file FILE@ADDRESS 	copy the bytes of FILE into data memory from a byte address, e.g. data.bin@0x200
+--load-memory FILE 	load data memory from an Intel HEX (.hex) or S-record (.srec, .s19) file before the program starts
+--dump-memory FILE 	write the non-zero words of data memory to an Intel HEX or S-record file once the program ends
+--vcd FILE 	write the PC, registers, flags and memory bus signals of every instruction to FILE as a Value Change Dump
+		for waveform viewers such as GTKWave. With --costs, each instruction lasts its cycles
+--output FORMAT 	run to the end and print the registers, flags, PC, exit reason, instruction count and memory
+		as json or yaml instead of tables. With --all, also print them after every instruction
 --help 		display help
 
 Found a bug? Feel free to raise an issue on https://github.com/coderick14/ARMed
 Contributions welcome :)`
 
 func main() {
-	var (
-		err error
-		choice string
-	)
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "assemble" {
+		assembleCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "link" {
+		linkCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		testCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		exportCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cosim" {
+		cosimCommand(os.Args[2:])
+		return
+	}
+
 	helpPtr := flag.Bool("help", false, "Display help")
 	allPtr := flag.Bool("all", false, "Display all registers after each instruction")
 	endPtr := flag.Bool("end", false, "Display registers only at end")
 	logPtr := flag.Bool("no-log", false, "Suppress log messages")
+	checkPtr := flag.Bool("check", false, "Check the program without running it")
+	pipelinePtr := flag.Bool("pipeline", false, "Simulate the five-stage pipeline")
+	forwardingPtr := flag.Bool("forwarding", false, "Enable forwarding in the pipeline")
+	var dataCaches, instructionCaches listFlag
+	flag.Var(&dataCaches, "cache", "Add a level to the data cache")
+	flag.Var(&instructionCaches, "icache", "Add a level to the instruction cache")
+	var predictors listFlag
+	flag.Var(&predictors, "predictor", "Add a branch predictor")
+	statsPtr := flag.String("stats", "", "Show instruction statistics as a table or as json")
+	costsPtr := flag.String("costs", "", "File with the cycle cost of each instruction class")
+	clockPtr := flag.String("clock", "", "Clock rate used to compute the execution time")
+	profilePtr := flag.Bool("profile", false, "Show where the instructions are executed")
+	foldedPtr := flag.String("folded", "", "File to write folded call stacks to")
+	var watchpoints listFlag
+	flag.Var(&watchpoints, "watch", "Add a watchpoint")
+	var limits Limits.Config
+	flag.Int64Var(&limits.MaxInstructions, "max-instructions", 0, "Maximum number of instructions to execute")
+	flag.DurationVar(&limits.MaxTime, "max-time", 0, "Maximum time to run for")
+	flag.Int64Var(&limits.MaxMemory, "max-memory", 0, "Maximum bytes of data memory to touch")
+	flag.Int64Var(&limits.MaxOutput, "max-output", 0, "Maximum bytes of logs to print")
+	flag.BoolVar(&limits.DetectLoops, "detect-loops", false, "Stop loops that never change the state")
+	servePtr := flag.String("serve", "", "Address to serve the web interface on")
+	tuiPtr := flag.Bool("tui", false, "Step through the program in a full-screen interface")
+	outputPtr := flag.String("output", "", "Print the final state as json or yaml")
+	var registerInputs, memoryInputs, memoryFiles listFlag
+	flag.Var(&registerInputs, "reg", "Set a register before the program starts")
+	flag.Var(&memoryInputs, "mem", "Set words of data memory before the program starts")
+	flag.Var(&memoryFiles, "mem-file", "Copy a file into data memory before the program starts")
+	var hexFiles listFlag
+	flag.Var(&hexFiles, "load-memory", "Load data memory from an Intel HEX or S-record file")
+	dumpPtr := flag.String("dump-memory", "", "Write data memory to an Intel HEX or S-record file")
+	vcdPtr := flag.String("vcd", "", "Write a Value Change Dump of the run")
 
 	flag.Parse()
 
@@ -82,76 +228,272 @@ func main() {
 		return
 	}
 
+	if *outputPtr != "" {
+		if name := outputConflict(); name != "" {
+			fmt.Println("Error : --" + name + " prints to standard output, so it cannot be used with --output")
+			os.Exit(2)
+		}
+	}
+
 	if len(flag.Args()) == 0 {
 		err = errors.New("Error : Missing filename.\n Type ARMed --help for further help")
 		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// every error is reported before the first instruction executes
+	program, err := buildProgram(flag.Args())
+	var warnings Assembler.ErrorList
+	if err == nil {
+		warnings, err = Memory.CheckProgram(program)
+	}
+	// warnings would break the json or yaml output
+	if len(warnings) != 0 && *outputPtr == "" {
+		fmt.Println(warnings)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *checkPtr == true {
+		fmt.Println("No errors found")
 		return
 	}
 
-	fileName := flag.Args()[0]
+	var watcher *Watch.Watcher
+	if len(watchpoints) != 0 {
+		watcher, err = newWatcher(watchpoints)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		Memory.AddObserver(watcher)
+	}
+
+	monitor := Limits.NewMonitor(limits)
+	if monitor.IsLimited() {
+		Memory.AddObserver(monitor)
+	}
+
+	if *servePtr != "" {
+		server, err := Server.New(program, func() error {
+			return setInputs(registerInputs, memoryInputs, memoryFiles, hexFiles)
+		}, watcher, monitor)
+		if err == nil {
+			err = server.ListenAndServe(*servePtr)
+		}
+		fmt.Println(err)
+		return
+	}
 
-	file, err := os.Open(fileName)
+	err = Memory.LoadProgram(program)
 	if err != nil {
-		fmt.Println("Error opening file : ", err)
+		fmt.Println(err)
 		return
 	}
-	defer file.Close()
 
-	reader := bufio.NewReader(file)
+	Memory.InitRegisters()
+	err = setInputs(registerInputs, memoryInputs, memoryFiles, hexFiles)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
-	for {
-		line, err := reader.ReadString(';')
-		if err == io.EOF {
-			if len(line) > 1 {
-				fmt.Println("Missing semicolon near :", line)
-				return
+	// models that follow the program print their results once it ends
+	var reports []func()
+	if *pipelinePtr == true {
+		pipeline := Pipeline.NewSimulator(*forwardingPtr)
+		Memory.AddObserver(pipeline)
+		reports = append(reports, pipeline.ShowDiagram, pipeline.ShowSummary)
+	}
+	for i, specs := range []listFlag{instructionCaches, dataCaches} {
+		if len(specs) == 0 {
+			continue
+		}
+		caches, err := newCacheHierarchy(i == 0, specs)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		Memory.AddObserver(caches)
+		reports = append(reports, caches.ShowStatistics)
+	}
+	if len(predictors) != 0 {
+		branchPredictors, err := newBranchPredictors(predictors)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		Memory.AddObserver(branchPredictors)
+		reports = append(reports, branchPredictors.ShowAccuracy)
+	}
+	if *statsPtr != "" {
+		statistics := Statistics.New()
+		Memory.AddObserver(statistics)
+		switch *statsPtr {
+		case "table":
+			reports = append(reports, statistics.ShowTable)
+		case "json":
+			reports = append(reports, statistics.ShowJSON)
+		default:
+			fmt.Println("Error : Unknown statistics format " + *statsPtr + ", expected table or json")
+			return
+		}
+	}
+	var cost func(execution *Memory.Execution) int64
+	if *costsPtr != "" || *clockPtr != "" {
+		timing, err := newTimingModel(*costsPtr, *clockPtr)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		Memory.AddObserver(timing)
+		reports = append(reports, timing.ShowSummary)
+		cost = timing.Cost
+	}
+	if *profilePtr == true {
+		profiler := Profiler.New()
+		Memory.AddObserver(profiler)
+		reports = append(reports, profiler.ShowProfile)
+		if *foldedPtr != "" {
+			reports = append(reports, func() {
+				err := profiler.WriteFoldedStacks(*foldedPtr)
+				if err != nil {
+					fmt.Println(err)
+				}
+			})
+		}
+	}
+
+	if *dumpPtr != "" {
+		_, err = HexFile.FormatOf(*dumpPtr)
+		if err != nil {
+			fmt.Println("Error :", err)
+			return
+		}
+		reports = append(reports, func() {
+			err := dumpMemoryImage(*dumpPtr)
+			if err != nil {
+				fmt.Println(err)
 			}
-			break
-		} else if err != nil {
-			fmt.Println("Error while reading file : ", err)
+		})
+	}
+
+	var waveform *Waveform.Writer
+	if *vcdPtr != "" {
+		waveform, err = Waveform.New(*vcdPtr, program, cost)
+		if err != nil {
+			fmt.Println(err)
 			return
 		}
-		line = strings.TrimSpace(strings.TrimRight(line, ";"))
-		if len(line) != 0 {
-			Memory.InstructionMem.Instructions = append(Memory.InstructionMem.Instructions, line)
+		// a run stopped by an error still writes the dump, up to the failing instruction
+		defer waveform.Close()
+		Memory.AddObserver(waveform)
+		reports = append(reports, func() {
+			err := waveform.Close()
+			if err != nil {
+				fmt.Println(err)
+			}
+		})
+	}
+
+	var writer *Output.Writer
+	if *outputPtr != "" {
+		writer, err = Output.NewWriter(os.Stdout, *outputPtr)
+		if err != nil {
+			fmt.Println(err)
+			return
 		}
 	}
 
-	Memory.InitRegisters()
-	Memory.InstructionMem.ExtractLabels()
+	// a program that fails still gets its reports, then exits with status 1
+	hasFailed := false
+	if *tuiPtr == true {
+		Memory.SaveRegisters()
+		err = TUI.New(monitor, watcher).Run()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		Memory.ShowRegisters(false)
+
+	} else if writer != nil {
+		// logs and prompts would mix with the output, so the program runs to the end silently
+		reason, message := Output.END_REASON, ""
+		for Memory.IsValidPC(Memory.InstructionMem.PC) {
+			location := Memory.InstructionMem.Locations[Memory.InstructionMem.PC]
+			err = Memory.InstructionMem.ValidateAndExecuteInstruction()
+			if err != nil {
+				reason, message = Output.ERROR_REASON, location.String()+": "+err.Error()
+				break
+			}
+			if violation := monitor.Violation(); violation != nil {
+				reason, message = Output.LIMIT_REASON, violation.Message
+				break
+			}
+			if *allPtr == true && Memory.IsValidPC(Memory.InstructionMem.PC) {
+				writer.Write(Output.Capture(Output.STEP_REASON, ""))
+			}
+		}
+		writer.Write(Output.Capture(reason, message))
+		hasFailed = reason == Output.ERROR_REASON
 
-	if *endPtr == true {
+	} else if *endPtr == true {
 		Memory.SaveRegisters()
 		for Memory.IsValidPC(Memory.InstructionMem.PC) {
 			if *logPtr == false {
-				fmt.Println("Executing :", Memory.InstructionMem.Instructions[Memory.InstructionMem.PC])
+				bytes, _ := fmt.Println("Executing :", Memory.InstructionMem.Describe(Memory.InstructionMem.PC))
+				monitor.AddOutput(bytes)
 			}
+			location := Memory.InstructionMem.Locations[Memory.InstructionMem.PC]
 			err = Memory.InstructionMem.ValidateAndExecuteInstruction()
 			if err != nil {
-				fmt.Println(err)
-				return
+				fmt.Println(location.String()+":", err)
+				Memory.ShowBacktrace()
+				hasFailed = true
+				break
+			}
+			if isStopped(monitor) {
+				break
+			}
+			if watcher != nil && watcher.ShouldBreak() && !askToContinue() {
+				break
 			}
 		}
-		Memory.ShowRegisters(false)
+		if !hasFailed {
+			Memory.ShowRegisters(false)
+		}
 
 	} else {
 
 		for Memory.IsValidPC(Memory.InstructionMem.PC) {
 			Memory.SaveRegisters()
 			if *logPtr == false {
-				fmt.Println("Executing :", Memory.InstructionMem.Instructions[Memory.InstructionMem.PC])
+				bytes, _ := fmt.Println("Executing :", Memory.InstructionMem.Describe(Memory.InstructionMem.PC))
+				monitor.AddOutput(bytes)
 			}
+			location := Memory.InstructionMem.Locations[Memory.InstructionMem.PC]
 			err = Memory.InstructionMem.ValidateAndExecuteInstruction()
 			if err != nil {
-				fmt.Println(err)
-				return
+				fmt.Println(location.String()+":", err)
+				Memory.ShowBacktrace()
+				hasFailed = true
+				break
 			}
 			Memory.ShowRegisters(*allPtr)
-			fmt.Printf("Continue [Y/n]? : ")
-			fmt.Scanln(&choice)
-			if choice == "n" {
+			if isStopped(monitor) || !askToContinue() {
 				break;
 			}
 		}
 	}
+
+	for _, report := range reports {
+		report()
+	}
+	if hasFailed {
+		os.Exit(1)
+	}
+	if violation := monitor.Violation(); violation != nil {
+		os.Exit(violation.Status)
+	}
 }
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	Cosim "github.com/coderick14/ARMed/Cosim"
+	Encoder "github.com/coderick14/ARMed/Encoder"
+	Grader "github.com/coderick14/ARMed/Grader"
+	Limits "github.com/coderick14/ARMed/Limits"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Function to assemble a source file, or read an object file, into an object.
+// The syntax of every instruction is checked, and all errors are returned together
+// with the object, which is nil if the file could not be read.
+func loadObject(fileName string) (*Assembler.Object, error) {
+	var object *Assembler.Object
+	var err error
+	if Assembler.IsObjectFile(fileName) {
+		object, err = Assembler.ReadObject(fileName)
+	} else {
+		preprocessor := Assembler.NewPreprocessor()
+		var statements []Assembler.Statement
+		statements, err = preprocessor.ExpandFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		object, err = Assembler.Assemble(statements, preprocessor.Symbols)
+	}
+	if object == nil {
+		return nil, err
+	}
+
+	errorList := Assembler.ErrorList{}.Append(err).Append(Memory.CheckInstructions(object.Text))
+	if len(errorList) != 0 {
+		return object, errorList
+	}
+	return object, nil
+}
+
+// Function to assemble and link source and object files into a single program.
+// The errors of all files are reported together, including invalid instructions.
+func buildProgram(fileNames []string) (*Assembler.Object, error) {
+	var objects []*Assembler.Object
+	var errorList Assembler.ErrorList
+	for _, fileName := range fileNames {
+		object, err := loadObject(fileName)
+		errorList = errorList.Append(err)
+		objects = append(objects, object)
+	}
+	if len(errorList) != 0 {
+		return nil, errorList
+	}
+	return Assembler.Link(objects)
+}
+
+// Function to run "ARMed assemble", which writes the object file for a single source file.
+func assembleCommand(args []string) {
+	flags := flag.NewFlagSet("assemble", flag.ExitOnError)
+	outputPtr := flags.String("o", "", "Output object file")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Println(errors.New("Error : Expected exactly one source file.\n Type ARMed --help for further help"))
+		return
+	}
+
+	fileName := flags.Arg(0)
+	outputName := *outputPtr
+	if outputName == "" {
+		outputName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".o"
+	}
+
+	object, err := loadObject(fileName)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = Assembler.WriteObject(outputName, object)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Function to run "ARMed link", which merges object files into a single program.
+func linkCommand(args []string) {
+	flags := flag.NewFlagSet("link", flag.ExitOnError)
+	outputPtr := flags.String("o", "a.out", "Output file")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Println(errors.New("Error : Missing object files.\n Type ARMed --help for further help"))
+		return
+	}
+
+	program, err := buildProgram(flags.Args())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = Assembler.WriteObject(*outputPtr, program)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Function to run "ARMed test", which runs the cases of a spec and reports which ones pass.
+// Exits with status 1 if the spec is invalid or a case fails.
+func testCommand(args []string) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Println(errors.New("Error : Expected exactly one spec file.\n Type ARMed --help for further help"))
+		os.Exit(1)
+	}
+
+	cases, err := Grader.ReadSpec(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// cases usually share a program, which is only built once
+	programs := make(map[string]*Assembler.Object)
+	var results []*Grader.Result
+	for _, testCase := range cases {
+		key := strings.Join(testCase.Programs, "\n")
+		program, isBuilt := programs[key]
+		if !isBuilt {
+			program, err = buildProgram(testCase.Programs)
+			if err == nil {
+				_, err = Memory.CheckProgram(program)
+			}
+			if err != nil {
+				results = append(results, Grader.Failed(testCase, err))
+				continue
+			}
+			programs[key] = program
+		}
+		results = append(results, Grader.Run(testCase, program))
+	}
+
+	if Grader.ShowResults(results) != nil {
+		os.Exit(1)
+	}
+}
+
+// Function to run "ARMed export", which writes the machine code of a program and its initial data memory
+// as images for a CPU built in Verilog, Logisim or Quartus. Without -text and -data, both are written
+// next to the first file, e.g. fact.text.mem and fact.data.mem.
+func exportCommand(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	formatPtr := flags.String("format", Encoder.READMEMH_FORMAT, "Image format : readmemh, readmemb, logisim or mif")
+	textPtr := flags.String("text", "", "Output image of instruction memory")
+	dataPtr := flags.String("data", "", "Output image of data memory")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Println(errors.New("Error : Missing source or object files.\n Type ARMed --help for further help"))
+		os.Exit(1)
+	}
+	extension, err := Encoder.Extension(*formatPtr)
+	if err != nil {
+		fmt.Println("Error :", err)
+		os.Exit(1)
+	}
+	textName, dataName := *textPtr, *dataPtr
+	if textName == "" && dataName == "" {
+		base := strings.TrimSuffix(flags.Arg(0), filepath.Ext(flags.Arg(0)))
+		textName, dataName = base+".text"+extension, base+".data"+extension
+	}
+
+	program, err := buildProgram(flags.Args())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if textName != "" {
+		words, err := Encoder.EncodeProgram(program)
+		if err == nil {
+			comments := make([]string, len(program.Text))
+			for i, statement := range program.Text {
+				comments[i] = statement.Text
+			}
+			err = Encoder.WriteImage(textName, *formatPtr, words, comments)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Wrote", len(words), "instructions to", textName)
+	}
+	if dataName != "" {
+		if len(program.Data) > Memory.MEMORY_SIZE-Memory.STACK_SIZE {
+			fmt.Println(errors.New("Error : Data section of " + strconv.Itoa(len(program.Data)) + " words does not fit in data memory"))
+			os.Exit(1)
+		}
+		// the whole of data memory is written, with the stack and unused words as zeros
+		words := make([]uint32, Memory.MEMORY_SIZE)
+		for i, word := range program.Data {
+			words[i] = uint32(word)
+		}
+		err = Encoder.WriteImage(dataName, *formatPtr, words, nil)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Wrote", len(words), "words of data memory to", dataName)
+	}
+}
+
+// Function to run "ARMed cosim", which checks the writes of a hardware simulation against ARMed running the same program.
+// Exits with status 1 if the trace diverges or the program can not run to its end.
+func cosimCommand(args []string) {
+	flags := flag.NewFlagSet("cosim", flag.ExitOnError)
+	signalsPtr := flags.String("signals", "", "Names of the signals of a VCD trace, e.g. clock=clk,reg_write=rf_we")
+	limitPtr := flags.Int64("max-instructions", Cosim.DEFAULT_MAX_INSTRUCTIONS, "Maximum number of instructions to execute")
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		fmt.Println(errors.New("Error : Expected a trace file and the files of the program.\n Type ARMed --help for further help"))
+		os.Exit(1)
+	}
+	signals, err := Cosim.ParseSignals(*signalsPtr)
+	if err != nil {
+		fmt.Println("Error :", err)
+		os.Exit(1)
+	}
+	events, err := Cosim.ReadTrace(flags.Arg(0), signals)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	program, err := buildProgram(flags.Args()[1:])
+	if err == nil {
+		_, err = Memory.CheckProgram(program)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	err = Cosim.ShowResult(Cosim.Run(program, events, *limitPtr))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// Flags that print to standard output while or after the program runs
+var printingFlags = []string{"pipeline", "cache", "icache", "predictor", "stats", "costs", "clock", "profile", "folded", "watch", "serve", "tui"}
+
+// Function to find a flag given on the command line that prints to standard output,
+// which --output keeps for the state of the machine. Returns "" if there is none.
+func outputConflict() string {
+	conflict := ""
+	flag.Visit(func(visited *flag.Flag) {
+		for _, name := range printingFlags {
+			if visited.Name == name && conflict == "" {
+				conflict = name
+			}
+		}
+	})
+	return conflict
+}
+
+// Function to ask whether to run the next instruction, printing the backtrace as often as asked.
+// Returns false if the answer is no.
+func askToContinue() bool {
+	var choice string
+	for {
+		fmt.Printf("Continue [Y/n/bt]? : ")
+		fmt.Scanln(&choice)
+		if choice != "bt" {
+			return choice != "n"
+		}
+		Memory.ShowBacktrace()
+		choice = ""
+	}
+}
+
+// Function to check if the program reached a limit. If it did, the limit, the loop
+// the program was last running and the backtrace are printed.
+func isStopped(monitor *Limits.Monitor) bool {
+	violation := monitor.Violation()
+	if violation == nil {
+		return false
+	}
+	fmt.Println("Error :", violation.Message)
+	if where := monitor.Where(); where != "" {
+		fmt.Println("Last loop : " + where)
+	}
+	Memory.ShowBacktrace()
+	return true
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	Assembler "github.com/coderick14/ARMed/Assembler"
+	HexFile "github.com/coderick14/ARMed/HexFile"
+	Memory "github.com/coderick14/ARMed/Memory"
+	"io/ioutil"
+	"strings"
+)
+
+// Function to set registers and data memory from the --reg, --mem, --mem-file and --load-memory flags.
+// Registers are given as X0=5, words as 0x100=1,2,3 and files as data.bin@0x200.
+// Values and addresses are constant expressions, and addresses are byte addresses.
+func setInputs(registerSpecs []string, memorySpecs []string, fileSpecs []string, hexFiles []string) error {
+	for _, spec := range registerSpecs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return errors.New("Error : Expected REGISTER=VALUE, found " + spec)
+		}
+		register, isRegister := Assembler.RegisterNumber(strings.TrimSpace(parts[0]))
+		if !isRegister {
+			return errors.New("Error : Unknown register " + parts[0] + " in " + spec)
+		}
+		value, err := Assembler.Evaluate(parts[1], nil)
+		if err != nil {
+			return errors.New("Error : Invalid value in " + spec + " : " + err.Error())
+		}
+		Memory.SetRegisterValue(register, value)
+	}
+
+	for _, spec := range memorySpecs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return errors.New("Error : Expected ADDRESS=WORD,WORD,..., found " + spec)
+		}
+		address, err := Assembler.Evaluate(parts[0], nil)
+		if err != nil {
+			return errors.New("Error : Invalid address in " + spec + " : " + err.Error())
+		}
+		words := strings.Split(parts[1], ",")
+		if address < 0 || address%Memory.WORD_SIZE != 0 || address/Memory.WORD_SIZE+int64(len(words)) > Memory.MEMORY_SIZE {
+			return errors.New("Error : Words at " + parts[0] + " must be word aligned and inside data memory")
+		}
+		for i, word := range words {
+			value, err := Assembler.Evaluate(word, nil)
+			if err != nil {
+				return errors.New("Error : Invalid word in " + spec + " : " + err.Error())
+			}
+			Memory.SetMemoryWord(address/Memory.WORD_SIZE+int64(i), int32(value))
+		}
+	}
+
+	for _, spec := range fileSpecs {
+		at := strings.LastIndex(spec, "@")
+		if at < 0 {
+			return errors.New("Error : Expected FILE@ADDRESS, found " + spec)
+		}
+		address, err := Assembler.Evaluate(spec[at+1:], nil)
+		if err != nil {
+			return errors.New("Error : Invalid address in " + spec + " : " + err.Error())
+		}
+		data, err := ioutil.ReadFile(spec[:at])
+		if err != nil {
+			return errors.New("Error reading file : " + err.Error())
+		}
+		err = Memory.SetMemoryBytes(address, data)
+		if err != nil {
+			return errors.New("Error : " + spec[:at] + " : " + err.Error())
+		}
+	}
+
+	for _, fileName := range hexFiles {
+		err := loadMemoryImage(fileName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Function to load data memory from an Intel HEX or S-record file.
+// Nothing is loaded if any record lies outside data memory.
+func loadMemoryImage(fileName string) error {
+	blocks, err := HexFile.Read(fileName)
+	if err != nil {
+		return errors.New("Error : " + err.Error())
+	}
+	last := int64(Memory.MEMORY_SIZE*Memory.WORD_SIZE - 1)
+	for _, block := range blocks {
+		if block.Address < 0 || block.End() > last {
+			return fmt.Errorf("Error : %s:%d: Bytes 0x%X to 0x%X are outside data memory, which ends at 0x%X", fileName, block.Line, block.Address, block.End(), last)
+		}
+	}
+	for _, block := range blocks {
+		Memory.SetMemoryBytes(block.Address, block.Data)
+	}
+	return nil
+}
+
+// Function to write the non-zero words of data memory to an Intel HEX or S-record file.
+func dumpMemoryImage(fileName string) error {
+	var blocks []HexFile.Block
+	var current *HexFile.Block
+	for index := int64(0); index < Memory.MEMORY_SIZE; index++ {
+		word := Memory.GetMemoryWord(index)
+		if word == 0 {
+			current = nil
+			continue
+		}
+		if current == nil {
+			blocks = append(blocks, HexFile.Block{Address: index * Memory.WORD_SIZE})
+			current = &blocks[len(blocks)-1]
+		}
+		current.Data = append(current.Data, byte(word>>24), byte(word>>16), byte(word>>8), byte(word))
+	}
+	return HexFile.Write(fileName, blocks)
+}
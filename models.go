@@ -0,0 +1,80 @@
+package main
+
+import (
+	Cache "github.com/coderick14/ARMed/Cache"
+	Predictor "github.com/coderick14/ARMed/Predictor"
+	Timing "github.com/coderick14/ARMed/Timing"
+	Watch "github.com/coderick14/ARMed/Watch"
+	"strings"
+)
+
+// Type to collect the values of a flag that may be given more than once
+type listFlag []string
+
+func (list *listFlag) String() string {
+	return strings.Join(*list, " ")
+}
+
+func (list *listFlag) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// Function to create a cache hierarchy with one level for each specification.
+func newCacheHierarchy(isInstruction bool, specs []string) (*Cache.Hierarchy, error) {
+	configs := make([]Cache.Config, len(specs))
+	for i, spec := range specs {
+		config, err := Cache.ParseConfig(spec)
+		if err != nil {
+			return nil, err
+		}
+		configs[i] = config
+	}
+	return Cache.NewHierarchy(isInstruction, configs), nil
+}
+
+// Function to create a simulator that runs each of the given branch predictors.
+func newBranchPredictors(specs []string) (*Predictor.Simulator, error) {
+	configs := make([]Predictor.Config, len(specs))
+	for i, spec := range specs {
+		config, err := Predictor.ParseConfig(spec)
+		if err != nil {
+			return nil, err
+		}
+		configs[i] = config
+	}
+	return Predictor.NewSimulator(configs), nil
+}
+
+// Function to create a timing model from a cost file and a clock rate, either of which may be empty.
+// The clock rate overrides the one in the cost file.
+func newTimingModel(costFile string, clockRate string) (*Timing.Model, error) {
+	config := Timing.DefaultConfig()
+	var err error
+	if costFile != "" {
+		config, err = Timing.ReadConfig(costFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if clockRate != "" {
+		config.ClockRate, err = Timing.ParseClockRate(clockRate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Timing.NewModel(config), nil
+}
+
+// Function to create a watcher for the given watchpoint specifications.
+func newWatcher(specs []string) (*Watch.Watcher, error) {
+	watchpoints := make([]*Watch.Watchpoint, len(specs))
+	for i, spec := range specs {
+		watchpoint, err := Watch.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		watchpoints[i] = watchpoint
+	}
+	return Watch.NewWatcher(watchpoints), nil
+}